@@ -0,0 +1,189 @@
+package ast
+
+type ModifierFunc func(Node) Node
+
+// Modify walks node depth-first, replacing every child node with the result
+// of calling modifier on it, and finally returns modifier(node). A child
+// replaced by a node of the wrong kind for its field, such as a statement
+// where an expression is expected, is set to nil.
+func Modify(node Node, modifier ModifierFunc) Node {
+	switch node := node.(type) {
+
+	case *Program:
+		for i, comment := range node.Comments {
+			node.Comments[i], _ = Modify(comment, modifier).(*Comment)
+		}
+		for i, statement := range node.Statements {
+			node.Statements[i], _ = Modify(statement, modifier).(Statement)
+		}
+
+	// Statements
+	case *LetStatement:
+		if node.Name != nil {
+			node.Name, _ = Modify(node.Name, modifier).(*Identifier)
+		}
+		if node.Pattern != nil {
+			node.Pattern, _ = Modify(node.Pattern, modifier).(Pattern)
+		}
+		node.Value = modifyExpression(node.Value, modifier)
+
+	case *ReturnStatement:
+		node.ReturnValue = modifyExpression(node.ReturnValue, modifier)
+
+	case *FunctionStatement:
+		node.Name, _ = Modify(node.Name, modifier).(*Identifier)
+		node.Function, _ = Modify(node.Function, modifier).(*FunctionLiteral)
+
+	case *ThrowStatement:
+		node.Value = modifyExpression(node.Value, modifier)
+
+	case *ExportStatement:
+		node.Statement, _ = Modify(node.Statement, modifier).(Statement)
+
+	case *ExpressionStatement:
+		node.Expression = modifyExpression(node.Expression, modifier)
+
+	case *BlockStatement:
+		for i := range node.Statements {
+			node.Statements[i], _ = Modify(node.Statements[i], modifier).(Statement)
+		}
+
+	case *WhileStatement:
+		node.Condition = modifyExpression(node.Condition, modifier)
+		node.Body = modifyBlock(node.Body, modifier)
+
+	case *ForStatement:
+		if node.Variable != nil {
+			node.Variable, _ = Modify(node.Variable, modifier).(*Identifier)
+		}
+		node.Iterable = modifyExpression(node.Iterable, modifier)
+		node.Body = modifyBlock(node.Body, modifier)
+
+	// Expressions
+	case *PrefixExpression:
+		node.Right = modifyExpression(node.Right, modifier)
+
+	case *InfixExpression:
+		node.Left = modifyExpression(node.Left, modifier)
+		node.Right = modifyExpression(node.Right, modifier)
+
+	case *AssignExpression:
+		node.Target = modifyExpression(node.Target, modifier)
+		node.Value = modifyExpression(node.Value, modifier)
+
+	case *IfExpression:
+		node.Condition = modifyExpression(node.Condition, modifier)
+		node.Consequence = modifyBlock(node.Consequence, modifier)
+		node.Alternative = modifyBlock(node.Alternative, modifier)
+
+	case *TryExpression:
+		node.Block = modifyBlock(node.Block, modifier)
+		if node.Param != nil {
+			node.Param, _ = Modify(node.Param, modifier).(*Identifier)
+		}
+		node.Catch = modifyBlock(node.Catch, modifier)
+		node.Finally = modifyBlock(node.Finally, modifier)
+
+	case *MatchExpression:
+		node.Subject = modifyExpression(node.Subject, modifier)
+		for _, arm := range node.Arms {
+			arm.Pattern, _ = Modify(arm.Pattern, modifier).(Pattern)
+			arm.Body = modifyBlock(arm.Body, modifier)
+		}
+
+	case *FunctionLiteral:
+		// the defaults are keyed by parameter name, which modifier may change
+		var defaults map[string]Expression
+		if node.Defaults != nil {
+			defaults = make(map[string]Expression, len(node.Defaults))
+		}
+		for i, param := range node.Parameters {
+			def, hasDefault := node.Defaults[param.Value]
+			node.Parameters[i], _ = Modify(param, modifier).(*Identifier)
+			if hasDefault && node.Parameters[i] != nil {
+				defaults[node.Parameters[i].Value] = modifyExpression(def, modifier)
+			}
+		}
+		node.Defaults = defaults
+		if node.Rest != nil {
+			node.Rest, _ = Modify(node.Rest, modifier).(*Identifier)
+		}
+		node.Body = modifyBlock(node.Body, modifier)
+
+	case *ImportExpression:
+		node.Path, _ = Modify(node.Path, modifier).(*StringLiteral)
+
+	case *MacroLiteral:
+		for i := range node.Parameters {
+			node.Parameters[i], _ = Modify(node.Parameters[i], modifier).(*Identifier)
+		}
+		node.Body = modifyBlock(node.Body, modifier)
+
+	case *CallExpression:
+		node.Function = modifyExpression(node.Function, modifier)
+		for i := range node.Arguments {
+			node.Arguments[i] = modifyExpression(node.Arguments[i], modifier)
+		}
+		for _, k := range node.Keywords {
+			k.Name, _ = Modify(k.Name, modifier).(*Identifier)
+			k.Value = modifyExpression(k.Value, modifier)
+		}
+
+	case *TemplateLiteral:
+		for i := range node.Parts {
+			node.Parts[i] = modifyExpression(node.Parts[i], modifier)
+		}
+
+	case *ArrayLiteral:
+		for i := range node.Elements {
+			node.Elements[i] = modifyExpression(node.Elements[i], modifier)
+		}
+
+	case *IndexExpression:
+		node.Left = modifyExpression(node.Left, modifier)
+		node.Index = modifyExpression(node.Index, modifier)
+
+	case *HashLiteral:
+		for _, pair := range node.Pairs {
+			pair.Key = modifyExpression(pair.Key, modifier)
+			pair.Value = modifyExpression(pair.Value, modifier)
+		}
+
+	// Patterns
+	case *LiteralPattern:
+		node.Value = modifyExpression(node.Value, modifier)
+
+	case *ArrayPattern:
+		for i := range node.Elements {
+			node.Elements[i], _ = Modify(node.Elements[i], modifier).(Pattern)
+		}
+		if node.Rest != nil {
+			node.Rest, _ = Modify(node.Rest, modifier).(*Identifier)
+		}
+
+	case *HashPattern:
+		for _, pair := range node.Pairs {
+			pair.Key = modifyExpression(pair.Key, modifier)
+			pair.Value, _ = Modify(pair.Value, modifier).(Pattern)
+		}
+
+	}
+
+	return modifier(node)
+}
+
+func modifyExpression(e Expression, modifier ModifierFunc) Expression {
+	if e == nil {
+		return nil
+	}
+	e, _ = Modify(e, modifier).(Expression)
+	return e
+}
+
+func modifyBlock(b *BlockStatement, modifier ModifierFunc) *BlockStatement {
+	if b == nil {
+		return nil
+	}
+	b, _ = Modify(b, modifier).(*BlockStatement)
+	return b
+}
@@ -0,0 +1,229 @@
+package ast
+
+// A Visitor's Visit method is invoked for each node encountered by Walk.
+// If the result visitor w is not nil, Walk visits each of the children
+// of node with the visitor w, followed by a call of w.Visit(nil).
+type Visitor interface {
+	Visit(node Node) (w Visitor)
+}
+
+// Walk traverses an AST in depth-first order, visiting children in the
+// order they appear in the source. The comments of a Program are visited
+// right before the first top-level statement that starts after them. It
+// starts by calling v.Visit(node); node must not be nil.
+func Walk(v Visitor, node Node) {
+	if v = v.Visit(node); v == nil {
+		return
+	}
+
+	switch n := node.(type) {
+	case *Program:
+		comments := n.Comments
+		for _, s := range n.Statements {
+			if s == nil {
+				continue
+			}
+			for len(comments) > 0 && comments[0].Pos().Offset < s.Pos().Offset {
+				Walk(v, comments[0])
+				comments = comments[1:]
+			}
+			Walk(v, s)
+		}
+		for _, c := range comments {
+			Walk(v, c)
+		}
+
+	case *Comment, *Identifier, *Boolean, *IntegerLiteral, *FloatLiteral,
+		*StringLiteral, *BreakStatement, *ContinueStatement:
+		// nothing to do
+
+	// Statements
+	case *LetStatement:
+		if n.Name != nil {
+			Walk(v, n.Name)
+		}
+		if n.Pattern != nil {
+			Walk(v, n.Pattern)
+		}
+		walkExpression(v, n.Value)
+
+	case *ReturnStatement:
+		walkExpression(v, n.ReturnValue)
+
+	case *FunctionStatement:
+		Walk(v, n.Name)
+		Walk(v, n.Function)
+
+	case *ThrowStatement:
+		walkExpression(v, n.Value)
+
+	case *ExportStatement:
+		if n.Statement != nil {
+			Walk(v, n.Statement)
+		}
+
+	case *ExpressionStatement:
+		walkExpression(v, n.Expression)
+
+	case *BlockStatement:
+		walkStatements(v, n.Statements)
+
+	case *WhileStatement:
+		walkExpression(v, n.Condition)
+		walkBlock(v, n.Body)
+
+	case *ForStatement:
+		if n.Variable != nil {
+			Walk(v, n.Variable)
+		}
+		walkExpression(v, n.Iterable)
+		walkBlock(v, n.Body)
+
+	// Expressions
+	case *PrefixExpression:
+		walkExpression(v, n.Right)
+
+	case *InfixExpression:
+		walkExpression(v, n.Left)
+		walkExpression(v, n.Right)
+
+	case *AssignExpression:
+		walkExpression(v, n.Target)
+		walkExpression(v, n.Value)
+
+	case *IfExpression:
+		walkExpression(v, n.Condition)
+		walkBlock(v, n.Consequence)
+		walkBlock(v, n.Alternative)
+
+	case *TryExpression:
+		walkBlock(v, n.Block)
+		if n.Param != nil {
+			Walk(v, n.Param)
+		}
+		walkBlock(v, n.Catch)
+		walkBlock(v, n.Finally)
+
+	case *MatchExpression:
+		walkExpression(v, n.Subject)
+		for _, arm := range n.Arms {
+			if arm.Pattern != nil {
+				Walk(v, arm.Pattern)
+			}
+			walkBlock(v, arm.Body)
+		}
+
+	case *FunctionLiteral:
+		for _, p := range n.Parameters {
+			Walk(v, p)
+			walkExpression(v, n.Defaults[p.Value])
+		}
+		if n.Rest != nil {
+			Walk(v, n.Rest)
+		}
+		walkBlock(v, n.Body)
+
+	case *ImportExpression:
+		if n.Path != nil {
+			Walk(v, n.Path)
+		}
+
+	case *MacroLiteral:
+		for _, p := range n.Parameters {
+			Walk(v, p)
+		}
+		walkBlock(v, n.Body)
+
+	case *CallExpression:
+		walkExpression(v, n.Function)
+		walkExpressions(v, n.Arguments)
+		for _, k := range n.Keywords {
+			Walk(v, k.Name)
+			walkExpression(v, k.Value)
+		}
+
+	case *TemplateLiteral:
+		walkExpressions(v, n.Parts)
+
+	case *ArrayLiteral:
+		walkExpressions(v, n.Elements)
+
+	case *IndexExpression:
+		walkExpression(v, n.Left)
+		walkExpression(v, n.Index)
+
+	case *HashLiteral:
+		for _, pair := range n.Pairs {
+			walkExpression(v, pair.Key)
+			walkExpression(v, pair.Value)
+		}
+
+	// Patterns
+	case *LiteralPattern:
+		walkExpression(v, n.Value)
+
+	case *ArrayPattern:
+		for _, el := range n.Elements {
+			Walk(v, el)
+		}
+		if n.Rest != nil {
+			Walk(v, n.Rest)
+		}
+
+	case *HashPattern:
+		for _, pair := range n.Pairs {
+			walkExpression(v, pair.Key)
+			if pair.Value != nil {
+				Walk(v, pair.Value)
+			}
+		}
+	}
+
+	v.Visit(nil)
+}
+
+// walkExpression, walkBlock and friends skip optional children that are
+// missing, such as the alternative of an if without else.
+
+func walkExpression(v Visitor, e Expression) {
+	if e != nil {
+		Walk(v, e)
+	}
+}
+
+func walkExpressions(v Visitor, list []Expression) {
+	for _, e := range list {
+		walkExpression(v, e)
+	}
+}
+
+func walkStatements(v Visitor, list []Statement) {
+	for _, s := range list {
+		if s != nil {
+			Walk(v, s)
+		}
+	}
+}
+
+func walkBlock(v Visitor, b *BlockStatement) {
+	if b != nil {
+		Walk(v, b)
+	}
+}
+
+type inspector func(Node) bool
+
+func (f inspector) Visit(node Node) Visitor {
+	if f(node) {
+		return f
+	}
+	return nil
+}
+
+// Inspect traverses an AST in depth-first order: It starts by calling
+// f(node); node must not be nil. If f returns true, Inspect invokes f
+// recursively for each of the non-nil children of node, followed by a
+// call of f(nil).
+func Inspect(node Node, f func(Node) bool) {
+	Walk(inspector(f), node)
+}
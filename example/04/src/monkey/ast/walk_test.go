@@ -0,0 +1,228 @@
+package ast_test
+
+import (
+	"fmt"
+	"monkey/ast"
+	"monkey/lexer"
+	"monkey/parser"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+)
+
+// allNodesInput contains at least one node of every kind in ast.go.
+const allNodesInput = `// a comment
+let x = 1;
+let [a, ...r] = [1, 2.5];
+let {k: v} = {"k": 1};
+fn f(p, q = 1, ...rest) { return p; }
+export let e = import "m";
+while (x < 1) { x += 1; break; }
+for (i in [1]) { continue; }
+let m = macro(a) { quote(a) };
+try { throw !true; } catch (err) { -1 } finally { 1 };
+match (x) { 1 => 1, [y] => y, {k: v} => v, _ => 0 };
+f(1, q: {"a": 1}["t${x}"]);
+if (x) { 1 } else { 2 };
+fn(z) { z }(1);
+`
+
+var allNodeKinds = []string{
+	"ArrayLiteral", "ArrayPattern", "AssignExpression", "BlockStatement",
+	"Boolean", "BreakStatement", "CallExpression", "Comment",
+	"ContinueStatement", "ExportStatement", "ExpressionStatement",
+	"FloatLiteral", "ForStatement", "FunctionLiteral", "FunctionStatement",
+	"HashLiteral", "HashPattern", "Identifier", "IfExpression",
+	"ImportExpression", "IndexExpression", "InfixExpression",
+	"IntegerLiteral", "LetStatement", "LiteralPattern", "MacroLiteral",
+	"MatchExpression", "PrefixExpression", "Program", "ReturnStatement",
+	"StringLiteral", "TemplateLiteral", "ThrowStatement", "TryExpression",
+	"WhileStatement",
+}
+
+func parse(t *testing.T, input string) *ast.Program {
+	l := lexer.New(input)
+	l.ScanComments()
+	p := parser.New(l)
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		t.Fatalf("parser errors: %v", p.Errors())
+	}
+	return program
+}
+
+func kind(node ast.Node) string {
+	return reflect.TypeOf(node).Elem().Name()
+}
+
+func sortedKinds(counts map[string]int) []string {
+	kinds := []string{}
+	for k := range counts {
+		kinds = append(kinds, k)
+	}
+	sort.Strings(kinds)
+	return kinds
+}
+
+func TestInspectVisitsAllNodeKinds(t *testing.T) {
+	program := parse(t, allNodesInput)
+
+	counts := map[string]int{}
+	ast.Inspect(program, func(node ast.Node) bool {
+		if node != nil {
+			counts[kind(node)]++
+		}
+		return true
+	})
+
+	got := sortedKinds(counts)
+	if !reflect.DeepEqual(got, allNodeKinds) {
+		t.Errorf("wrong node kinds.\nwant=%v\ngot= %v", allNodeKinds, got)
+	}
+}
+
+type depthVisitor struct {
+	depth    *int
+	maxDepth *int
+}
+
+func (v depthVisitor) Visit(node ast.Node) ast.Visitor {
+	if node == nil {
+		*v.depth--
+		return nil
+	}
+	*v.depth++
+	if *v.depth > *v.maxDepth {
+		*v.maxDepth = *v.depth
+	}
+	return v
+}
+
+func TestWalkVisitsCommentsInSourceOrder(t *testing.T) {
+	program := parse(t, `// a
+let x = 1; // b
+fn f() {
+  // c
+}
+/* d */ f();
+// e`)
+
+	visited := []string{}
+	ast.Inspect(program, func(node ast.Node) bool {
+		switch node := node.(type) {
+		case *ast.Comment:
+			visited = append(visited, node.Token.Literal)
+		case *ast.LetStatement, *ast.FunctionStatement, *ast.ExpressionStatement:
+			visited = append(visited, strings.Fields(node.String())[0])
+		}
+		return true
+	})
+
+	expected := "// a, let, // b, fn, // c, /* d */, f(), // e"
+	if strings.Join(visited, ", ") != expected {
+		t.Errorf("wrong visiting order. want=%q, got=%q",
+			expected, strings.Join(visited, ", "))
+	}
+}
+
+func TestWalkBalancesVisits(t *testing.T) {
+	program := parse(t, "let x = -(1 + 2) * 3;")
+
+	depth, maxDepth := 0, 0
+	ast.Walk(depthVisitor{&depth, &maxDepth}, program)
+
+	if depth != 0 {
+		t.Errorf("unbalanced Visit(nil) calls. depth=%d", depth)
+	}
+
+	// Program > LetStatement > InfixExpression * > PrefixExpression >
+	// InfixExpression + > IntegerLiteral
+	if maxDepth != 6 {
+		t.Errorf("wrong maximum depth. want=6, got=%d", maxDepth)
+	}
+}
+
+func TestInspectOrderAndPruning(t *testing.T) {
+	program := parse(t, "f(a, b + c, k: fn(d) { e });")
+
+	visited := []string{}
+	ast.Inspect(program, func(node ast.Node) bool {
+		if node == nil {
+			return false
+		}
+		if ident, ok := node.(*ast.Identifier); ok {
+			visited = append(visited, ident.Value)
+		}
+		_, isFunction := node.(*ast.FunctionLiteral)
+		return !isFunction
+	})
+
+	expected := "f a b c k"
+	if strings.Join(visited, " ") != expected {
+		t.Errorf("wrong visiting order. want=%q, got=%q",
+			expected, strings.Join(visited, " "))
+	}
+}
+
+func TestModifyVisitsAllNodeKinds(t *testing.T) {
+	inspected := map[string]int{}
+	ast.Inspect(parse(t, allNodesInput), func(node ast.Node) bool {
+		if node != nil {
+			inspected[kind(node)]++
+		}
+		return true
+	})
+
+	modified := map[string]int{}
+	ast.Modify(parse(t, allNodesInput), func(node ast.Node) ast.Node {
+		modified[kind(node)]++
+		return node
+	})
+
+	if !reflect.DeepEqual(modified, inspected) {
+		t.Errorf("Modify and Inspect disagree.\nInspect=%v\nModify= %v",
+			inspected, modified)
+	}
+}
+
+func TestModifyReplacesInEveryNodeKind(t *testing.T) {
+	program := parse(t, allNodesInput)
+
+	ast.Modify(program, func(node ast.Node) ast.Node {
+		if integer, ok := node.(*ast.IntegerLiteral); ok && integer.Value == 1 {
+			integer.Value = 2
+			integer.Token.Literal = "2"
+		}
+		return node
+	})
+
+	ast.Inspect(program, func(node ast.Node) bool {
+		if integer, ok := node.(*ast.IntegerLiteral); ok && integer.Value == 1 {
+			t.Errorf("integer literal at %s not modified", integer.Pos())
+		}
+		return true
+	})
+
+	expected := parse(t, strings.Replace(allNodesInput, "1", "2", -1))
+	if program.String() != expected.String() {
+		t.Errorf("wrong result.\nwant=%q\ngot= %q", expected.String(), program.String())
+	}
+}
+
+func ExampleInspect() {
+	p := parser.New(lexer.New("let total = price * (1 + tax);"))
+	program := p.ParseProgram()
+
+	ast.Inspect(program, func(node ast.Node) bool {
+		if ident, ok := node.(*ast.Identifier); ok {
+			fmt.Println(ident.Value)
+		}
+		return true
+	})
+
+	// Output:
+	// total
+	// price
+	// tax
+}
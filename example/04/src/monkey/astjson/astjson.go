@@ -0,0 +1,474 @@
+// Package astjson converts Monkey tokens and syntax trees to and from JSON.
+//
+// The schema is stable: fields are only ever added, never renamed or removed.
+//
+// A position is an object with the fields of token.Position:
+//
+//	{"filename": "main.monkey", "offset": 4, "line": 1, "column": 5}
+//
+// where "filename" is left out when empty. A token is
+//
+//	{"type": "IDENT", "literal": "x", "pos": <position>, "end": <position>}
+//
+// with the token.TokenType as "type", such as "IDENT", "LET" or "+=". A token
+// stream is an array of tokens ending with the EOF token.
+//
+// Every ast.Node is an object whose "kind" is the name of its Go type, such as
+// "LetStatement", followed by the "pos" and "end" of the node, the "token" it
+// starts with, and then one field for each field of the Go type, named like
+// the Go field with a lower-case first letter. Child nodes are nested node
+// objects, lists of nodes are arrays and nil values are null. For example,
+// "x + 1;" is encoded as
+//
+//	{
+//	  "kind": "ExpressionStatement",
+//	  "pos": {"offset": 0, "line": 1, "column": 1},
+//	  "end": {"offset": 6, "line": 1, "column": 7},
+//	  "token": {"type": "IDENT", "literal": "x", ...},
+//	  "expression": {
+//	    "kind": "InfixExpression",
+//	    ...
+//	    "left": {"kind": "Identifier", ..., "value": "x"},
+//	    "operator": "+",
+//	    "right": {"kind": "IntegerLiteral", ..., "value": 1}
+//	  }
+//	}
+//
+// The fields of each kind are:
+//
+//	Program              statements, comments
+//	Comment              token
+//	LetStatement         token, name, pattern, value
+//	ReturnStatement      token, returnValue
+//	FunctionStatement    token, name, function
+//	ThrowStatement       token, value
+//	ExportStatement      token, statement
+//	ExpressionStatement  token, expression
+//	BlockStatement       token, statements, rbrace
+//	WhileStatement       token, condition, body
+//	ForStatement         token, variable, iterable, body
+//	BreakStatement       token
+//	ContinueStatement    token
+//	Identifier           token, value
+//	Boolean              token, value
+//	IntegerLiteral       token, value, big
+//	FloatLiteral         token, value
+//	StringLiteral        token, value
+//	TemplateLiteral      token, parts
+//	PrefixExpression     token, operator, right
+//	InfixExpression      token, left, operator, right
+//	AssignExpression     token, target, operator, value
+//	IfExpression         token, condition, consequence, alternative
+//	TryExpression        token, block, param, catch, finally
+//	MatchExpression      token, subject, arms, rbrace
+//	LiteralPattern       value
+//	ArrayPattern         token, elements, rest, rbracket
+//	HashPattern          token, pairs, rbrace
+//	FunctionLiteral      token, parameters, defaults, rest, body, name
+//	ImportExpression     token, path
+//	MacroLiteral         token, parameters, body
+//	CallExpression       token, function, arguments, keywords, rparen
+//	ArrayLiteral         token, elements, rbracket
+//	IndexExpression      token, left, index, rbracket
+//	HashLiteral          token, pairs, rbrace
+//
+// Program and LiteralPattern have no "token" field. The "rbrace", "rbracket"
+// and "rparen" fields are the closing tokens. The "defaults" of a
+// FunctionLiteral are an object from parameter names to nodes. The elements
+// of "arms" are {"pattern", "body"} objects, the "pairs" are {"key", "value"}
+// objects and the "keywords" are {"name", "value"} objects. The "big" field of
+// an IntegerLiteral is null unless the value does not fit into an int64, in
+// which case it is the value as a JSON number and "value" is 0.
+package astjson
+
+import (
+	"bytes"
+	"encoding/json"
+	"monkey/ast"
+	"monkey/lexer"
+	"monkey/token"
+	"reflect"
+	"sort"
+)
+
+type jsonPosition struct {
+	Filename string `json:"filename,omitempty"`
+	Offset   int    `json:"offset"`
+	Line     int    `json:"line"`
+	Column   int    `json:"column"`
+}
+
+type jsonToken struct {
+	Type    token.TokenType `json:"type"`
+	Literal string          `json:"literal"`
+	Pos     jsonPosition    `json:"pos"`
+	End     jsonPosition    `json:"end"`
+}
+
+// object is a JSON object which keeps its fields in order.
+type object []field
+
+type field struct {
+	name  string
+	value interface{}
+}
+
+func (o object) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+
+	buf.WriteByte('{')
+	for i, f := range o {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+
+		name, err := marshal(f.name)
+		if err != nil {
+			return nil, err
+		}
+		value, err := marshal(f.value)
+		if err != nil {
+			return nil, err
+		}
+
+		buf.Write(name)
+		buf.WriteByte(':')
+		buf.Write(value)
+	}
+	buf.WriteByte('}')
+
+	return buf.Bytes(), nil
+}
+
+// marshal is json.Marshal without escaping <, > and &, which are common in
+// operators.
+func marshal(v interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(v); err != nil {
+		return nil, err
+	}
+
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n")), nil
+}
+
+// Tokens reads all tokens from l, up to and including the EOF token.
+func Tokens(l *lexer.Lexer) []token.Token {
+	tokens := []token.Token{}
+	for {
+		tok := l.NextToken()
+		tokens = append(tokens, tok)
+		if tok.Type == token.EOF {
+			return tokens
+		}
+	}
+}
+
+// MarshalTokens returns the JSON encoding of a token stream.
+func MarshalTokens(tokens []token.Token) ([]byte, error) {
+	encoded := make([]jsonToken, len(tokens))
+	for i, tok := range tokens {
+		encoded[i] = encodeToken(tok)
+	}
+	return marshal(encoded)
+}
+
+// Marshal returns the JSON encoding of node and all of its children.
+func Marshal(node ast.Node) ([]byte, error) {
+	return marshal(encodeNode(node))
+}
+
+func encodePosition(pos token.Position) jsonPosition {
+	return jsonPosition{
+		Filename: pos.Filename,
+		Offset:   pos.Offset,
+		Line:     pos.Line,
+		Column:   pos.Column,
+	}
+}
+
+func encodeToken(tok token.Token) jsonToken {
+	return jsonToken{
+		Type:    tok.Type,
+		Literal: tok.Literal,
+		Pos:     encodePosition(tok.Pos),
+		End:     encodePosition(tok.End),
+	}
+}
+
+func isNil(node ast.Node) bool {
+	if node == nil {
+		return true
+	}
+	v := reflect.ValueOf(node)
+	return v.Kind() == reflect.Ptr && v.IsNil()
+}
+
+func kind(node ast.Node) string {
+	return reflect.TypeOf(node).Elem().Name()
+}
+
+// list encodes the n nodes returned by node, or null if the slice they come
+// from is nil.
+func list(n int, null bool, node func(i int) ast.Node) interface{} {
+	if null {
+		return nil
+	}
+
+	items := make([]interface{}, n)
+	for i := range items {
+		items[i] = encodeNode(node(i))
+	}
+	return items
+}
+
+func statements(stmts []ast.Statement) interface{} {
+	return list(len(stmts), stmts == nil, func(i int) ast.Node { return stmts[i] })
+}
+
+func expressions(exps []ast.Expression) interface{} {
+	return list(len(exps), exps == nil, func(i int) ast.Node { return exps[i] })
+}
+
+func identifiers(idents []*ast.Identifier) interface{} {
+	return list(len(idents), idents == nil, func(i int) ast.Node { return idents[i] })
+}
+
+func patterns(pats []ast.Pattern) interface{} {
+	return list(len(pats), pats == nil, func(i int) ast.Node { return pats[i] })
+}
+
+// pairs encodes n objects with the two fields in names, or null if the slice
+// they come from is nil.
+func pairs(n int, null bool, names [2]string, pair func(i int) (ast.Node, ast.Node)) interface{} {
+	if null {
+		return nil
+	}
+
+	items := make([]interface{}, n)
+	for i := range items {
+		first, second := pair(i)
+		items[i] = object{
+			{names[0], encodeNode(first)},
+			{names[1], encodeNode(second)},
+		}
+	}
+	return items
+}
+
+func encodeNode(node ast.Node) interface{} {
+	if isNil(node) {
+		return nil
+	}
+
+	o := object{
+		{"kind", kind(node)},
+		{"pos", encodePosition(node.Pos())},
+		{"end", encodePosition(node.End())},
+	}
+	add := func(name string, value interface{}) {
+		o = append(o, field{name, value})
+	}
+	tok := func(t token.Token) {
+		add("token", encodeToken(t))
+	}
+
+	switch n := node.(type) {
+	case *ast.Program:
+		comments := list(len(n.Comments), n.Comments == nil,
+			func(i int) ast.Node { return n.Comments[i] })
+		add("statements", statements(n.Statements))
+		add("comments", comments)
+
+	case *ast.Comment:
+		tok(n.Token)
+
+	case *ast.LetStatement:
+		tok(n.Token)
+		add("name", encodeNode(n.Name))
+		add("pattern", encodeNode(n.Pattern))
+		add("value", encodeNode(n.Value))
+
+	case *ast.ReturnStatement:
+		tok(n.Token)
+		add("returnValue", encodeNode(n.ReturnValue))
+
+	case *ast.FunctionStatement:
+		tok(n.Token)
+		add("name", encodeNode(n.Name))
+		add("function", encodeNode(n.Function))
+
+	case *ast.ThrowStatement:
+		tok(n.Token)
+		add("value", encodeNode(n.Value))
+
+	case *ast.ExportStatement:
+		tok(n.Token)
+		add("statement", encodeNode(n.Statement))
+
+	case *ast.ExpressionStatement:
+		tok(n.Token)
+		add("expression", encodeNode(n.Expression))
+
+	case *ast.BlockStatement:
+		tok(n.Token)
+		add("statements", statements(n.Statements))
+		add("rbrace", encodeToken(n.Rbrace))
+
+	case *ast.WhileStatement:
+		tok(n.Token)
+		add("condition", encodeNode(n.Condition))
+		add("body", encodeNode(n.Body))
+
+	case *ast.ForStatement:
+		tok(n.Token)
+		add("variable", encodeNode(n.Variable))
+		add("iterable", encodeNode(n.Iterable))
+		add("body", encodeNode(n.Body))
+
+	case *ast.BreakStatement:
+		tok(n.Token)
+
+	case *ast.ContinueStatement:
+		tok(n.Token)
+
+	case *ast.Identifier:
+		tok(n.Token)
+		add("value", n.Value)
+
+	case *ast.Boolean:
+		tok(n.Token)
+		add("value", n.Value)
+
+	case *ast.IntegerLiteral:
+		tok(n.Token)
+		add("value", n.Value)
+		add("big", n.Big)
+
+	case *ast.FloatLiteral:
+		tok(n.Token)
+		add("value", n.Value)
+
+	case *ast.StringLiteral:
+		tok(n.Token)
+		add("value", n.Value)
+
+	case *ast.TemplateLiteral:
+		tok(n.Token)
+		add("parts", expressions(n.Parts))
+
+	case *ast.PrefixExpression:
+		tok(n.Token)
+		add("operator", n.Operator)
+		add("right", encodeNode(n.Right))
+
+	case *ast.InfixExpression:
+		tok(n.Token)
+		add("left", encodeNode(n.Left))
+		add("operator", n.Operator)
+		add("right", encodeNode(n.Right))
+
+	case *ast.AssignExpression:
+		tok(n.Token)
+		add("target", encodeNode(n.Target))
+		add("operator", n.Operator)
+		add("value", encodeNode(n.Value))
+
+	case *ast.IfExpression:
+		tok(n.Token)
+		add("condition", encodeNode(n.Condition))
+		add("consequence", encodeNode(n.Consequence))
+		add("alternative", encodeNode(n.Alternative))
+
+	case *ast.TryExpression:
+		tok(n.Token)
+		add("block", encodeNode(n.Block))
+		add("param", encodeNode(n.Param))
+		add("catch", encodeNode(n.Catch))
+		add("finally", encodeNode(n.Finally))
+
+	case *ast.MatchExpression:
+		arms := pairs(len(n.Arms), n.Arms == nil, [2]string{"pattern", "body"},
+			func(i int) (ast.Node, ast.Node) { return n.Arms[i].Pattern, n.Arms[i].Body })
+		tok(n.Token)
+		add("subject", encodeNode(n.Subject))
+		add("arms", arms)
+		add("rbrace", encodeToken(n.Rbrace))
+
+	case *ast.LiteralPattern:
+		add("value", encodeNode(n.Value))
+
+	case *ast.ArrayPattern:
+		tok(n.Token)
+		add("elements", patterns(n.Elements))
+		add("rest", encodeNode(n.Rest))
+		add("rbracket", encodeToken(n.Rbracket))
+
+	case *ast.HashPattern:
+		tok(n.Token)
+		add("pairs", pairs(len(n.Pairs), n.Pairs == nil, [2]string{"key", "value"},
+			func(i int) (ast.Node, ast.Node) { return n.Pairs[i].Key, n.Pairs[i].Value }))
+		add("rbrace", encodeToken(n.Rbrace))
+
+	case *ast.FunctionLiteral:
+		var defaults interface{}
+		if n.Defaults != nil {
+			names := make([]string, 0, len(n.Defaults))
+			for name := range n.Defaults {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+
+			encoded := object{}
+			for _, name := range names {
+				encoded = append(encoded, field{name, encodeNode(n.Defaults[name])})
+			}
+			defaults = encoded
+		}
+		tok(n.Token)
+		add("parameters", identifiers(n.Parameters))
+		add("defaults", defaults)
+		add("rest", encodeNode(n.Rest))
+		add("body", encodeNode(n.Body))
+		add("name", n.Name)
+
+	case *ast.ImportExpression:
+		tok(n.Token)
+		add("path", encodeNode(n.Path))
+
+	case *ast.MacroLiteral:
+		tok(n.Token)
+		add("parameters", identifiers(n.Parameters))
+		add("body", encodeNode(n.Body))
+
+	case *ast.CallExpression:
+		keywords := pairs(len(n.Keywords), n.Keywords == nil, [2]string{"name", "value"},
+			func(i int) (ast.Node, ast.Node) { return n.Keywords[i].Name, n.Keywords[i].Value })
+		tok(n.Token)
+		add("function", encodeNode(n.Function))
+		add("arguments", expressions(n.Arguments))
+		add("keywords", keywords)
+		add("rparen", encodeToken(n.Rparen))
+
+	case *ast.ArrayLiteral:
+		tok(n.Token)
+		add("elements", expressions(n.Elements))
+		add("rbracket", encodeToken(n.Rbracket))
+
+	case *ast.IndexExpression:
+		tok(n.Token)
+		add("left", encodeNode(n.Left))
+		add("index", encodeNode(n.Index))
+		add("rbracket", encodeToken(n.Rbracket))
+
+	case *ast.HashLiteral:
+		tok(n.Token)
+		add("pairs", pairs(len(n.Pairs), n.Pairs == nil, [2]string{"key", "value"},
+			func(i int) (ast.Node, ast.Node) { return n.Pairs[i].Key, n.Pairs[i].Value }))
+		add("rbrace", encodeToken(n.Rbrace))
+	}
+
+	return o
+}
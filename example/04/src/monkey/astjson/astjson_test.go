@@ -0,0 +1,198 @@
+package astjson
+
+import (
+	"bytes"
+	"encoding/json"
+	"monkey/ast"
+	"monkey/lexer"
+	"monkey/parser"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// allNodesInput contains at least one node of every kind in ast.go.
+const allNodesInput = `// a comment
+let x = 1;
+let big = 100000000000000000000;
+let [a, ...r] = [1, 2.5];
+let {k: v} = {"k": 1, 2: true};
+fn f(p, q = 1, ...rest) { return p; }
+export let e = import "m";
+while (x < 1) { x += 1; break; }
+for (i in [1]) { continue; }
+let m = macro(a) { quote(a) };
+try { throw !true; } catch (err) { -1 } finally { 1 };
+match (x) { 1 => 1, [y] => y, {k: v} => v, _ => 0 };
+f(1, q: {"a": 1}["t${x}"]);
+if (x <= 1) { 1 } else if (x) { 2 } else { 3 };
+fn(z) { z }(1);
+/* done */`
+
+func parse(t *testing.T, filename, input string) *ast.Program {
+	l := lexer.NewFile(filename, input)
+	l.ScanComments()
+	p := parser.New(l)
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		t.Fatalf("parser errors: %v", p.Errors())
+	}
+	return program
+}
+
+func TestProgramRoundTrip(t *testing.T) {
+	for _, filename := range []string{"", "all.monkey"} {
+		program := parse(t, filename, allNodesInput)
+
+		data, err := Marshal(program)
+		if err != nil {
+			t.Fatalf("Marshal returned error: %s", err)
+		}
+
+		decoded, err := UnmarshalProgram(data)
+		if err != nil {
+			t.Fatalf("UnmarshalProgram returned error: %s", err)
+		}
+
+		if !reflect.DeepEqual(decoded, program) {
+			t.Errorf("decoded program differs from the original.\noriginal=%q\ndecoded=%q",
+				program.String(), decoded.String())
+		}
+
+		again, err := Marshal(decoded)
+		if err != nil {
+			t.Fatalf("Marshal returned error: %s", err)
+		}
+		if !bytes.Equal(data, again) {
+			t.Errorf("encoding the decoded program gives different JSON")
+		}
+	}
+}
+
+func TestMarshalCoversAllNodeKinds(t *testing.T) {
+	data, err := Marshal(parse(t, "", allNodesInput))
+	if err != nil {
+		t.Fatalf("Marshal returned error: %s", err)
+	}
+
+	var tree interface{}
+	if err := json.Unmarshal(data, &tree); err != nil {
+		t.Fatalf("Marshal produced invalid JSON: %s", err)
+	}
+
+	kinds := map[string]bool{}
+	var collect func(v interface{})
+	collect = func(v interface{}) {
+		switch v := v.(type) {
+		case map[string]interface{}:
+			if k, ok := v["kind"].(string); ok {
+				kinds[k] = true
+			}
+			for _, child := range v {
+				collect(child)
+			}
+		case []interface{}:
+			for _, child := range v {
+				collect(child)
+			}
+		}
+	}
+	collect(tree)
+
+	if len(kinds) != 35 {
+		t.Errorf("wrong number of node kinds. want=35, got=%d: %v", len(kinds), kinds)
+	}
+}
+
+func TestMarshalSchema(t *testing.T) {
+	data, err := Marshal(parse(t, "", "x <= 1;"))
+	if err != nil {
+		t.Fatalf("Marshal returned error: %s", err)
+	}
+
+	expected := `{"kind":"Program",` +
+		`"pos":{"offset":0,"line":1,"column":1},"end":{"offset":6,"line":1,"column":7},` +
+		`"statements":[{"kind":"ExpressionStatement",` +
+		`"pos":{"offset":0,"line":1,"column":1},"end":{"offset":6,"line":1,"column":7},` +
+		`"token":{"type":"IDENT","literal":"x","pos":{"offset":0,"line":1,"column":1},"end":{"offset":1,"line":1,"column":2}},` +
+		`"expression":{"kind":"InfixExpression",` +
+		`"pos":{"offset":0,"line":1,"column":1},"end":{"offset":6,"line":1,"column":7},` +
+		`"token":{"type":"<=","literal":"<=","pos":{"offset":2,"line":1,"column":3},"end":{"offset":4,"line":1,"column":5}},` +
+		`"left":{"kind":"Identifier",` +
+		`"pos":{"offset":0,"line":1,"column":1},"end":{"offset":1,"line":1,"column":2},` +
+		`"token":{"type":"IDENT","literal":"x","pos":{"offset":0,"line":1,"column":1},"end":{"offset":1,"line":1,"column":2}},` +
+		`"value":"x"},` +
+		`"operator":"<=",` +
+		`"right":{"kind":"IntegerLiteral",` +
+		`"pos":{"offset":5,"line":1,"column":6},"end":{"offset":6,"line":1,"column":7},` +
+		`"token":{"type":"INT","literal":"1","pos":{"offset":5,"line":1,"column":6},"end":{"offset":6,"line":1,"column":7}},` +
+		`"value":1,"big":null}}}],` +
+		`"comments":null}`
+
+	if string(data) != expected {
+		t.Errorf("wrong JSON.\nexpected=%s\ngot=     %s", expected, data)
+	}
+}
+
+func TestTokensRoundTrip(t *testing.T) {
+	l := lexer.NewFile("t.monkey", "let s = \"a${b}c\"; // <&>\n")
+	l.ScanComments()
+	tokens := Tokens(l)
+
+	data, err := MarshalTokens(tokens)
+	if err != nil {
+		t.Fatalf("MarshalTokens returned error: %s", err)
+	}
+
+	if !bytes.Contains(data, []byte(`{"type":"COMMENT","literal":"// <&>"`)) {
+		t.Errorf("comment token not found in %s", data)
+	}
+
+	decoded, err := UnmarshalTokens(data)
+	if err != nil {
+		t.Fatalf("UnmarshalTokens returned error: %s", err)
+	}
+
+	if !reflect.DeepEqual(decoded, tokens) {
+		t.Errorf("decoded tokens differ.\noriginal=%v\ndecoded=%v", tokens, decoded)
+	}
+
+	if last := decoded[len(decoded)-1]; last.Type != "EOF" {
+		t.Errorf("token stream does not end with EOF. got=%q", last.Type)
+	}
+}
+
+func TestUnmarshalErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`{"kind":"Nonsense"}`, `unknown node kind "Nonsense"`},
+		{`{}`, `unknown node kind ""`},
+		{
+			`{"kind":"ExpressionStatement","expression":{"kind":"BreakStatement"}}`,
+			"expected an expression, got BreakStatement",
+		},
+		{
+			`{"kind":"WhileStatement","body":{"kind":"Identifier","value":"x"}}`,
+			"expected BlockStatement, got Identifier",
+		},
+		{`{"kind":"Identifier","value":1}`, "json: cannot unmarshal number"},
+	}
+
+	for _, tt := range tests {
+		_, err := Unmarshal([]byte(tt.input))
+		if err == nil {
+			t.Errorf("expected error for %s", tt.input)
+			continue
+		}
+		if !strings.HasPrefix(err.Error(), tt.expected) {
+			t.Errorf("wrong error for %s. expected=%q, got=%q", tt.input, tt.expected, err.Error())
+		}
+	}
+
+	_, err := UnmarshalProgram([]byte(`{"kind":"BreakStatement"}`))
+	if err == nil || err.Error() != "expected Program, got BreakStatement" {
+		t.Errorf("wrong error from UnmarshalProgram. got=%v", err)
+	}
+}
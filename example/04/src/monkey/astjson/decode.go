@@ -0,0 +1,541 @@
+package astjson
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"monkey/ast"
+	"monkey/token"
+)
+
+// UnmarshalTokens decodes a token stream encoded by MarshalTokens.
+func UnmarshalTokens(data []byte) ([]token.Token, error) {
+	var encoded []jsonToken
+	if err := json.Unmarshal(data, &encoded); err != nil {
+		return nil, err
+	}
+
+	tokens := make([]token.Token, len(encoded))
+	for i, tok := range encoded {
+		tokens[i] = decodeToken(tok)
+	}
+	return tokens, nil
+}
+
+// Unmarshal decodes a node encoded by Marshal. The "pos" and "end" fields are
+// ignored, since they follow from the tokens of the node.
+func Unmarshal(data []byte) (ast.Node, error) {
+	d := &decoder{}
+	node := d.node(data)
+	if d.err != nil {
+		return nil, d.err
+	}
+	return node, nil
+}
+
+// UnmarshalProgram is like Unmarshal but requires the node to be a Program.
+func UnmarshalProgram(data []byte) (*ast.Program, error) {
+	node, err := Unmarshal(data)
+	if err != nil {
+		return nil, err
+	}
+
+	program, ok := node.(*ast.Program)
+	if !ok {
+		return nil, fmt.Errorf("expected Program, got %s", kindOf(node))
+	}
+	return program, nil
+}
+
+func decodePosition(pos jsonPosition) token.Position {
+	return token.Position{
+		Filename: pos.Filename,
+		Offset:   pos.Offset,
+		Line:     pos.Line,
+		Column:   pos.Column,
+	}
+}
+
+func decodeToken(tok jsonToken) token.Token {
+	return token.Token{
+		Type:    tok.Type,
+		Literal: tok.Literal,
+		Pos:     decodePosition(tok.Pos),
+		End:     decodePosition(tok.End),
+	}
+}
+
+func kindOf(node ast.Node) string {
+	if node == nil {
+		return "null"
+	}
+	return kind(node)
+}
+
+// decoder keeps the first error it runs into; once it has one, every method
+// returns zero values.
+type decoder struct {
+	err error
+}
+
+func (d *decoder) fail(format string, a ...interface{}) {
+	if d.err == nil {
+		d.err = fmt.Errorf(format, a...)
+	}
+}
+
+func isNull(data json.RawMessage) bool {
+	return len(data) == 0 || string(bytes.TrimSpace(data)) == "null"
+}
+
+func (d *decoder) unmarshal(data json.RawMessage, v interface{}) {
+	if d.err != nil || isNull(data) {
+		return
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		d.err = err
+	}
+}
+
+func (d *decoder) token(data json.RawMessage) token.Token {
+	var tok jsonToken
+	d.unmarshal(data, &tok)
+	return decodeToken(tok)
+}
+
+func (d *decoder) list(data json.RawMessage) []json.RawMessage {
+	var items []json.RawMessage
+	d.unmarshal(data, &items)
+	return items
+}
+
+func (d *decoder) statement(data json.RawMessage) ast.Statement {
+	node := d.node(data)
+	if node == nil {
+		return nil
+	}
+	stmt, ok := node.(ast.Statement)
+	if !ok {
+		d.fail("expected a statement, got %s", kind(node))
+	}
+	return stmt
+}
+
+func (d *decoder) expression(data json.RawMessage) ast.Expression {
+	node := d.node(data)
+	if node == nil {
+		return nil
+	}
+	exp, ok := node.(ast.Expression)
+	if !ok {
+		d.fail("expected an expression, got %s", kind(node))
+	}
+	return exp
+}
+
+func (d *decoder) pattern(data json.RawMessage) ast.Pattern {
+	node := d.node(data)
+	if node == nil {
+		return nil
+	}
+	pattern, ok := node.(ast.Pattern)
+	if !ok {
+		d.fail("expected a pattern, got %s", kind(node))
+	}
+	return pattern
+}
+
+func (d *decoder) identifier(data json.RawMessage) *ast.Identifier {
+	node := d.node(data)
+	if node == nil {
+		return nil
+	}
+	ident, ok := node.(*ast.Identifier)
+	if !ok {
+		d.fail("expected Identifier, got %s", kind(node))
+	}
+	return ident
+}
+
+func (d *decoder) block(data json.RawMessage) *ast.BlockStatement {
+	node := d.node(data)
+	if node == nil {
+		return nil
+	}
+	block, ok := node.(*ast.BlockStatement)
+	if !ok {
+		d.fail("expected BlockStatement, got %s", kind(node))
+	}
+	return block
+}
+
+func (d *decoder) statements(data json.RawMessage) []ast.Statement {
+	items := d.list(data)
+	if items == nil {
+		return nil
+	}
+
+	stmts := make([]ast.Statement, len(items))
+	for i, item := range items {
+		stmts[i] = d.statement(item)
+	}
+	return stmts
+}
+
+func (d *decoder) expressions(data json.RawMessage) []ast.Expression {
+	items := d.list(data)
+	if items == nil {
+		return nil
+	}
+
+	exps := make([]ast.Expression, len(items))
+	for i, item := range items {
+		exps[i] = d.expression(item)
+	}
+	return exps
+}
+
+func (d *decoder) identifiers(data json.RawMessage) []*ast.Identifier {
+	items := d.list(data)
+	if items == nil {
+		return nil
+	}
+
+	idents := make([]*ast.Identifier, len(items))
+	for i, item := range items {
+		idents[i] = d.identifier(item)
+	}
+	return idents
+}
+
+func (d *decoder) patterns(data json.RawMessage) []ast.Pattern {
+	items := d.list(data)
+	if items == nil {
+		return nil
+	}
+
+	patterns := make([]ast.Pattern, len(items))
+	for i, item := range items {
+		patterns[i] = d.pattern(item)
+	}
+	return patterns
+}
+
+// fields splits a JSON object into its fields, or returns nil for null.
+func (d *decoder) fields(data json.RawMessage) map[string]json.RawMessage {
+	var fields map[string]json.RawMessage
+	d.unmarshal(data, &fields)
+	return fields
+}
+
+func (d *decoder) node(data json.RawMessage) ast.Node {
+	f := d.fields(data)
+	if f == nil || d.err != nil {
+		return nil
+	}
+
+	var kind string
+	d.unmarshal(f["kind"], &kind)
+
+	switch kind {
+	case "Program":
+		program := &ast.Program{Statements: d.statements(f["statements"])}
+		if items := d.list(f["comments"]); items != nil {
+			program.Comments = make([]*ast.Comment, len(items))
+			for i, item := range items {
+				comment, ok := d.node(item).(*ast.Comment)
+				if !ok {
+					d.fail("expected Comment in comments")
+				}
+				program.Comments[i] = comment
+			}
+		}
+		return program
+
+	case "Comment":
+		return &ast.Comment{Token: d.token(f["token"])}
+
+	case "LetStatement":
+		return &ast.LetStatement{
+			Token:   d.token(f["token"]),
+			Name:    d.identifier(f["name"]),
+			Pattern: d.pattern(f["pattern"]),
+			Value:   d.expression(f["value"]),
+		}
+
+	case "ReturnStatement":
+		return &ast.ReturnStatement{
+			Token:       d.token(f["token"]),
+			ReturnValue: d.expression(f["returnValue"]),
+		}
+
+	case "FunctionStatement":
+		stmt := &ast.FunctionStatement{
+			Token: d.token(f["token"]),
+			Name:  d.identifier(f["name"]),
+		}
+		if function := d.expression(f["function"]); function != nil {
+			fl, ok := function.(*ast.FunctionLiteral)
+			if !ok {
+				d.fail("expected FunctionLiteral, got %s", kindOf(function))
+			}
+			stmt.Function = fl
+		}
+		return stmt
+
+	case "ThrowStatement":
+		return &ast.ThrowStatement{
+			Token: d.token(f["token"]),
+			Value: d.expression(f["value"]),
+		}
+
+	case "ExportStatement":
+		return &ast.ExportStatement{
+			Token:     d.token(f["token"]),
+			Statement: d.statement(f["statement"]),
+		}
+
+	case "ExpressionStatement":
+		return &ast.ExpressionStatement{
+			Token:      d.token(f["token"]),
+			Expression: d.expression(f["expression"]),
+		}
+
+	case "BlockStatement":
+		return &ast.BlockStatement{
+			Token:      d.token(f["token"]),
+			Statements: d.statements(f["statements"]),
+			Rbrace:     d.token(f["rbrace"]),
+		}
+
+	case "WhileStatement":
+		return &ast.WhileStatement{
+			Token:     d.token(f["token"]),
+			Condition: d.expression(f["condition"]),
+			Body:      d.block(f["body"]),
+		}
+
+	case "ForStatement":
+		return &ast.ForStatement{
+			Token:    d.token(f["token"]),
+			Variable: d.identifier(f["variable"]),
+			Iterable: d.expression(f["iterable"]),
+			Body:     d.block(f["body"]),
+		}
+
+	case "BreakStatement":
+		return &ast.BreakStatement{Token: d.token(f["token"])}
+
+	case "ContinueStatement":
+		return &ast.ContinueStatement{Token: d.token(f["token"])}
+
+	case "Identifier":
+		ident := &ast.Identifier{Token: d.token(f["token"])}
+		d.unmarshal(f["value"], &ident.Value)
+		return ident
+
+	case "Boolean":
+		boolean := &ast.Boolean{Token: d.token(f["token"])}
+		d.unmarshal(f["value"], &boolean.Value)
+		return boolean
+
+	case "IntegerLiteral":
+		lit := &ast.IntegerLiteral{Token: d.token(f["token"])}
+		d.unmarshal(f["value"], &lit.Value)
+		d.unmarshal(f["big"], &lit.Big)
+		return lit
+
+	case "FloatLiteral":
+		lit := &ast.FloatLiteral{Token: d.token(f["token"])}
+		d.unmarshal(f["value"], &lit.Value)
+		return lit
+
+	case "StringLiteral":
+		lit := &ast.StringLiteral{Token: d.token(f["token"])}
+		d.unmarshal(f["value"], &lit.Value)
+		return lit
+
+	case "TemplateLiteral":
+		return &ast.TemplateLiteral{
+			Token: d.token(f["token"]),
+			Parts: d.expressions(f["parts"]),
+		}
+
+	case "PrefixExpression":
+		exp := &ast.PrefixExpression{
+			Token: d.token(f["token"]),
+			Right: d.expression(f["right"]),
+		}
+		d.unmarshal(f["operator"], &exp.Operator)
+		return exp
+
+	case "InfixExpression":
+		exp := &ast.InfixExpression{
+			Token: d.token(f["token"]),
+			Left:  d.expression(f["left"]),
+			Right: d.expression(f["right"]),
+		}
+		d.unmarshal(f["operator"], &exp.Operator)
+		return exp
+
+	case "AssignExpression":
+		exp := &ast.AssignExpression{
+			Token:  d.token(f["token"]),
+			Target: d.expression(f["target"]),
+			Value:  d.expression(f["value"]),
+		}
+		d.unmarshal(f["operator"], &exp.Operator)
+		return exp
+
+	case "IfExpression":
+		return &ast.IfExpression{
+			Token:       d.token(f["token"]),
+			Condition:   d.expression(f["condition"]),
+			Consequence: d.block(f["consequence"]),
+			Alternative: d.block(f["alternative"]),
+		}
+
+	case "TryExpression":
+		return &ast.TryExpression{
+			Token:   d.token(f["token"]),
+			Block:   d.block(f["block"]),
+			Param:   d.identifier(f["param"]),
+			Catch:   d.block(f["catch"]),
+			Finally: d.block(f["finally"]),
+		}
+
+	case "MatchExpression":
+		exp := &ast.MatchExpression{
+			Token:   d.token(f["token"]),
+			Subject: d.expression(f["subject"]),
+			Rbrace:  d.token(f["rbrace"]),
+		}
+		if items := d.list(f["arms"]); items != nil {
+			exp.Arms = make([]*ast.MatchArm, len(items))
+			for i, item := range items {
+				arm := d.fields(item)
+				exp.Arms[i] = &ast.MatchArm{
+					Pattern: d.pattern(arm["pattern"]),
+					Body:    d.block(arm["body"]),
+				}
+			}
+		}
+		return exp
+
+	case "LiteralPattern":
+		return &ast.LiteralPattern{Value: d.expression(f["value"])}
+
+	case "ArrayPattern":
+		return &ast.ArrayPattern{
+			Token:    d.token(f["token"]),
+			Elements: d.patterns(f["elements"]),
+			Rest:     d.identifier(f["rest"]),
+			Rbracket: d.token(f["rbracket"]),
+		}
+
+	case "HashPattern":
+		pattern := &ast.HashPattern{
+			Token:  d.token(f["token"]),
+			Rbrace: d.token(f["rbrace"]),
+		}
+		if items := d.list(f["pairs"]); items != nil {
+			pattern.Pairs = make([]*ast.HashPatternPair, len(items))
+			for i, item := range items {
+				pair := d.fields(item)
+				pattern.Pairs[i] = &ast.HashPatternPair{
+					Key:   d.expression(pair["key"]),
+					Value: d.pattern(pair["value"]),
+				}
+			}
+		}
+		return pattern
+
+	case "FunctionLiteral":
+		lit := &ast.FunctionLiteral{
+			Token:      d.token(f["token"]),
+			Parameters: d.identifiers(f["parameters"]),
+			Rest:       d.identifier(f["rest"]),
+			Body:       d.block(f["body"]),
+		}
+		if defaults := d.fields(f["defaults"]); defaults != nil {
+			lit.Defaults = make(map[string]ast.Expression, len(defaults))
+			for name, value := range defaults {
+				lit.Defaults[name] = d.expression(value)
+			}
+		}
+		d.unmarshal(f["name"], &lit.Name)
+		return lit
+
+	case "ImportExpression":
+		exp := &ast.ImportExpression{Token: d.token(f["token"])}
+		if path := d.expression(f["path"]); path != nil {
+			lit, ok := path.(*ast.StringLiteral)
+			if !ok {
+				d.fail("expected StringLiteral, got %s", kindOf(path))
+			}
+			exp.Path = lit
+		}
+		return exp
+
+	case "MacroLiteral":
+		return &ast.MacroLiteral{
+			Token:      d.token(f["token"]),
+			Parameters: d.identifiers(f["parameters"]),
+			Body:       d.block(f["body"]),
+		}
+
+	case "CallExpression":
+		exp := &ast.CallExpression{
+			Token:     d.token(f["token"]),
+			Function:  d.expression(f["function"]),
+			Arguments: d.expressions(f["arguments"]),
+			Rparen:    d.token(f["rparen"]),
+		}
+		if items := d.list(f["keywords"]); items != nil {
+			exp.Keywords = make([]*ast.KeywordArgument, len(items))
+			for i, item := range items {
+				kw := d.fields(item)
+				exp.Keywords[i] = &ast.KeywordArgument{
+					Name:  d.identifier(kw["name"]),
+					Value: d.expression(kw["value"]),
+				}
+			}
+		}
+		return exp
+
+	case "ArrayLiteral":
+		return &ast.ArrayLiteral{
+			Token:    d.token(f["token"]),
+			Elements: d.expressions(f["elements"]),
+			Rbracket: d.token(f["rbracket"]),
+		}
+
+	case "IndexExpression":
+		return &ast.IndexExpression{
+			Token:    d.token(f["token"]),
+			Left:     d.expression(f["left"]),
+			Index:    d.expression(f["index"]),
+			Rbracket: d.token(f["rbracket"]),
+		}
+
+	case "HashLiteral":
+		hash := &ast.HashLiteral{
+			Token:  d.token(f["token"]),
+			Rbrace: d.token(f["rbrace"]),
+		}
+		if items := d.list(f["pairs"]); items != nil {
+			hash.Pairs = make([]*ast.HashLiteralPair, len(items))
+			for i, item := range items {
+				pair := d.fields(item)
+				hash.Pairs[i] = &ast.HashLiteralPair{
+					Key:   d.expression(pair["key"]),
+					Value: d.expression(pair["value"]),
+				}
+			}
+		}
+		return hash
+	}
+
+	d.fail("unknown node kind %q", kind)
+	return nil
+}
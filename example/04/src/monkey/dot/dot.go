@@ -0,0 +1,204 @@
+// Package dot renders syntax trees and call graphs in the Graphviz DOT
+// language, for viewing with tools like "dot -Tsvg".
+package dot
+
+import (
+	"bytes"
+	"fmt"
+	"monkey/ast"
+	"monkey/object"
+	"strconv"
+	"strings"
+)
+
+// AST renders node and all of its children as a digraph. Every node is
+// labelled with its kind and, for identifiers, literals and operators, the
+// text it stands for. Children are laid out left to right in source order, so
+// that the shape of the tree shows how the parser grouped an expression.
+// Comments are left out.
+func AST(node ast.Node) string {
+	var out bytes.Buffer
+
+	out.WriteString("digraph AST {\n")
+	out.WriteString("  ordering=out;\n")
+	out.WriteString("  node [shape=box, fontname=\"monospace\"];\n")
+
+	var parents []int
+	next := 0
+	ast.Inspect(node, func(n ast.Node) bool {
+		if n == nil {
+			parents = parents[:len(parents)-1]
+			return false
+		}
+		if _, ok := n.(*ast.Comment); ok {
+			return false
+		}
+
+		id := next
+		next++
+
+		fmt.Fprintf(&out, "  n%d [label=%s];\n", id, quote(label(n)))
+		if len(parents) > 0 {
+			fmt.Fprintf(&out, "  n%d -> n%d;\n", parents[len(parents)-1], id)
+		}
+		parents = append(parents, id)
+
+		return true
+	})
+
+	out.WriteString("}\n")
+
+	return out.String()
+}
+
+func label(node ast.Node) string {
+	kind := strings.TrimPrefix(fmt.Sprintf("%T", node), "*ast.")
+
+	var detail string
+	switch node := node.(type) {
+	case *ast.Identifier:
+		detail = node.Value
+	case *ast.IntegerLiteral:
+		detail = node.TokenLiteral()
+	case *ast.FloatLiteral:
+		detail = node.TokenLiteral()
+	case *ast.Boolean:
+		detail = strconv.FormatBool(node.Value)
+	case *ast.StringLiteral:
+		detail = strconv.Quote(node.Value)
+	case *ast.PrefixExpression:
+		detail = node.Operator
+	case *ast.InfixExpression:
+		detail = node.Operator
+	case *ast.AssignExpression:
+		detail = node.Operator
+	case *ast.FunctionLiteral:
+		detail = node.Name
+	}
+
+	if detail == "" {
+		return kind
+	}
+	return kind + "\n" + detail
+}
+
+var escaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// quote returns s as a DOT string, in which \n is a line break.
+func quote(s string) string {
+	return `"` + escaper.Replace(s) + `"`
+}
+
+// CallGraph records which functions call which while a program is evaluated.
+// Set it as the Observer in the object.Options of the environment passed to
+// evaluator.Eval. Functions are told apart by where they are defined, so all
+// closures created by the same function literal are one node.
+type CallGraph struct {
+	labels  []string            // the labels of the nodes, by id
+	builtin []bool              // whether the node with that id is a builtin
+	ids     map[interface{}]int // node ids by function body or builtin
+	edges   []*callEdge         // in the order of the first call
+	index   map[[2]int]*callEdge
+	stack   []int // the ids of the functions being called
+}
+
+type callEdge struct {
+	from, to int
+	count    int
+}
+
+// NewCallGraph returns an empty call graph. Calls made outside of any
+// function come from a node labelled <program>.
+func NewCallGraph() *CallGraph {
+	return &CallGraph{
+		labels:  []string{"<program>"},
+		builtin: []bool{false},
+		ids:     map[interface{}]int{},
+		index:   map[[2]int]*callEdge{},
+		stack:   []int{0},
+	}
+}
+
+func (g *CallGraph) EnterCall(call *ast.CallExpression, fn object.Object) {
+	id := g.node(call, fn)
+	from := g.stack[len(g.stack)-1]
+
+	edge, ok := g.index[[2]int{from, id}]
+	if !ok {
+		edge = &callEdge{from: from, to: id}
+		g.index[[2]int{from, id}] = edge
+		g.edges = append(g.edges, edge)
+	}
+	edge.count++
+
+	g.stack = append(g.stack, id)
+}
+
+func (g *CallGraph) ExitCall(call *ast.CallExpression, fn object.Object) {
+	g.stack = g.stack[:len(g.stack)-1]
+}
+
+// node returns the id of the node for fn, adding one if fn is called for the
+// first time.
+func (g *CallGraph) node(call *ast.CallExpression, fn object.Object) int {
+	var key interface{} = fn
+	var label string
+	builtin := false
+
+	switch fn := fn.(type) {
+	case *object.Function:
+		key = fn.Body
+		label = fn.Name
+		if label == "" {
+			label = "<anonymous>"
+		}
+		if pos := fn.Body.Pos(); pos.IsValid() {
+			label += "\n" + pos.String()
+		}
+	case *object.Builtin:
+		builtin = true
+		label = "builtin"
+		if ident, ok := call.Function.(*ast.Identifier); ok {
+			label = ident.Value
+		}
+	default:
+		key = fn.Type()
+		label = string(fn.Type())
+	}
+
+	if id, ok := g.ids[key]; ok {
+		return id
+	}
+
+	id := len(g.labels)
+	g.ids[key] = id
+	g.labels = append(g.labels, label)
+	g.builtin = append(g.builtin, builtin)
+
+	return id
+}
+
+// String renders the graph as a digraph whose edges are labelled with the
+// number of calls. Builtins are drawn with dashed lines.
+func (g *CallGraph) String() string {
+	var out bytes.Buffer
+
+	out.WriteString("digraph calls {\n")
+	out.WriteString("  node [shape=box, fontname=\"monospace\"];\n")
+
+	for id, label := range g.labels {
+		style := ""
+		if g.builtin[id] {
+			style = ", style=dashed"
+		}
+		fmt.Fprintf(&out, "  n%d [label=%s%s];\n", id, quote(label), style)
+	}
+
+	for _, edge := range g.edges {
+		fmt.Fprintf(&out, "  n%d -> n%d [label=\"%d\"];\n", edge.from, edge.to, edge.count)
+	}
+
+	out.WriteString("}\n")
+
+	return out.String()
+}
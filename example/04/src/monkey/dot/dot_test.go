@@ -0,0 +1,90 @@
+package dot
+
+import (
+	"monkey/evaluator"
+	"monkey/lexer"
+	"monkey/object"
+	"monkey/parser"
+	"strings"
+	"testing"
+)
+
+func TestAST(t *testing.T) {
+	l := lexer.New(`-a * "b\"" + c; // comment`)
+	l.ScanComments()
+	p := parser.New(l)
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		t.Fatalf("parser errors: %v", p.Errors())
+	}
+
+	expected := `digraph AST {
+  ordering=out;
+  node [shape=box, fontname="monospace"];
+  n0 [label="Program"];
+  n1 [label="ExpressionStatement"];
+  n0 -> n1;
+  n2 [label="InfixExpression\n+"];
+  n1 -> n2;
+  n3 [label="InfixExpression\n*"];
+  n2 -> n3;
+  n4 [label="PrefixExpression\n-"];
+  n3 -> n4;
+  n5 [label="Identifier\na"];
+  n4 -> n5;
+  n6 [label="StringLiteral\n\"b\\\"\""];
+  n3 -> n6;
+  n7 [label="Identifier\nc"];
+  n2 -> n7;
+}
+`
+
+	if got := AST(program); got != expected {
+		t.Errorf("wrong DOT.\nexpected=\n%s\ngot=\n%s", expected, got)
+	}
+}
+
+func TestCallGraph(t *testing.T) {
+	input := `
+fn fib(n) {
+  if (n < 2) { return n; }
+  fib(n - 1) + fib(n - 2)
+}
+let twice = fn(f, x) { f(f(x)) };
+twice(fn(x) { len([x]) }, 1);
+fib(3);
+`
+	program := parser.New(lexer.NewFile("calls.monkey", input)).ParseProgram()
+
+	graph := NewCallGraph()
+	env := object.NewEnvironment()
+	env.Options().Observer = graph
+
+	evaluated := evaluator.Eval(program, env)
+	if err, ok := evaluated.(*object.Error); ok {
+		t.Fatalf("evaluation failed: %s", err.Inspect())
+	}
+
+	expected := `digraph calls {
+  node [shape=box, fontname="monospace"];
+  n0 [label="<program>"];
+  n1 [label="twice\ncalls.monkey:6:22"];
+  n2 [label="<anonymous>\ncalls.monkey:7:13"];
+  n3 [label="len", style=dashed];
+  n4 [label="fib\ncalls.monkey:2:11"];
+  n0 -> n1 [label="1"];
+  n1 -> n2 [label="2"];
+  n2 -> n3 [label="2"];
+  n0 -> n4 [label="1"];
+  n4 -> n4 [label="4"];
+}
+`
+
+	if got := graph.String(); got != expected {
+		t.Errorf("wrong DOT.\nexpected=\n%s\ngot=\n%s", expected, got)
+	}
+
+	if !strings.HasPrefix(AST(program), "digraph AST {") {
+		t.Errorf("AST does not render a digraph")
+	}
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"monkey/astjson"
+	"monkey/lexer"
+	"monkey/parser"
+	"os"
+)
+
+// runTokens implements "monkey tokens [file]", which prints the tokens of the
+// file, or standard input, as JSON.
+func runTokens(args []string) int {
+	filename, input, err := readInput(args)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	l := lexer.NewFile(filename, string(input))
+	l.ScanComments()
+
+	data, err := astjson.MarshalTokens(astjson.Tokens(l))
+	return printJSON(data, err)
+}
+
+// runAST implements "monkey ast [file]", which prints the syntax tree of the
+// file, or standard input, as JSON.
+func runAST(args []string) int {
+	filename, input, err := readInput(args)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	l := lexer.NewFile(filename, string(input))
+	l.ScanComments()
+	p := parser.New(l)
+
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		for _, msg := range p.Errors() {
+			fmt.Fprintln(os.Stderr, msg)
+		}
+		return 1
+	}
+
+	data, err := astjson.Marshal(program)
+	return printJSON(data, err)
+}
+
+// readInput reads the file named by the only argument, or standard input if
+// there is none.
+func readInput(args []string) (string, []byte, error) {
+	switch len(args) {
+	case 0:
+		input, err := ioutil.ReadAll(os.Stdin)
+		return "", input, err
+	case 1:
+		input, err := ioutil.ReadFile(args[0])
+		return args[0], input, err
+	default:
+		return "", nil, fmt.Errorf("expected at most one file, got %d", len(args))
+	}
+}
+
+func printJSON(data []byte, err error) int {
+	var out bytes.Buffer
+	if err == nil {
+		err = json.Indent(&out, data, "", "  ")
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	out.WriteByte('\n')
+	out.WriteTo(os.Stdout)
+	return 0
+}
@@ -2,13 +2,16 @@ package evaluator
 
 import (
 	"fmt"
+	"math"
+	"math/big"
 	"monkey/object"
+	"unicode/utf8"
 )
 
 var builtins = map[string]*object.Builtin{
 	"len": &object.Builtin{Fn: func(args ...object.Object) object.Object {
 		if len(args) != 1 {
-			return newError("wrong number of arguments. got=%d, want=1",
+			return newError(object.ARGUMENT_ERROR, "wrong number of arguments. got=%d, want=1",
 				len(args))
 		}
 
@@ -16,13 +19,28 @@ var builtins = map[string]*object.Builtin{
 		case *object.Array:
 			return &object.Integer{Value: int64(len(arg.Elements))}
 		case *object.String:
-			return &object.Integer{Value: int64(len(arg.Value))}
+			return &object.Integer{Value: int64(utf8.RuneCountInString(arg.Value))}
 		default:
-			return newError("argument to `len` not supported, got %s",
+			return newError(object.TYPE_ERROR, "argument to `len` not supported, got %s",
 				args[0].Type())
 		}
 	},
 	},
+	"bytelen": &object.Builtin{
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) != 1 {
+				return newError(object.ARGUMENT_ERROR, "wrong number of arguments. got=%d, want=1",
+					len(args))
+			}
+			if args[0].Type() != object.STRING_OBJ {
+				return newError(object.TYPE_ERROR, "argument to `bytelen` must be STRING, got %s",
+					args[0].Type())
+			}
+
+			str := args[0].(*object.String)
+			return &object.Integer{Value: int64(len(str.Value))}
+		},
+	},
 	"puts": &object.Builtin{
 		Fn: func(args ...object.Object) object.Object {
 			for _, arg := range args {
@@ -35,11 +53,11 @@ var builtins = map[string]*object.Builtin{
 	"first": &object.Builtin{
 		Fn: func(args ...object.Object) object.Object {
 			if len(args) != 1 {
-				return newError("wrong number of arguments. got=%d, want=1",
+				return newError(object.ARGUMENT_ERROR, "wrong number of arguments. got=%d, want=1",
 					len(args))
 			}
 			if args[0].Type() != object.ARRAY_OBJ {
-				return newError("argument to `first` must be ARRAY, got %s",
+				return newError(object.TYPE_ERROR, "argument to `first` must be ARRAY, got %s",
 					args[0].Type())
 			}
 
@@ -54,11 +72,11 @@ var builtins = map[string]*object.Builtin{
 	"last": &object.Builtin{
 		Fn: func(args ...object.Object) object.Object {
 			if len(args) != 1 {
-				return newError("wrong number of arguments. got=%d, want=1",
+				return newError(object.ARGUMENT_ERROR, "wrong number of arguments. got=%d, want=1",
 					len(args))
 			}
 			if args[0].Type() != object.ARRAY_OBJ {
-				return newError("argument to `last` must be ARRAY, got %s",
+				return newError(object.TYPE_ERROR, "argument to `last` must be ARRAY, got %s",
 					args[0].Type())
 			}
 
@@ -74,11 +92,11 @@ var builtins = map[string]*object.Builtin{
 	"rest": &object.Builtin{
 		Fn: func(args ...object.Object) object.Object {
 			if len(args) != 1 {
-				return newError("wrong number of arguments. got=%d, want=1",
+				return newError(object.ARGUMENT_ERROR, "wrong number of arguments. got=%d, want=1",
 					len(args))
 			}
 			if args[0].Type() != object.ARRAY_OBJ {
-				return newError("argument to `rest` must be ARRAY, got %s",
+				return newError(object.TYPE_ERROR, "argument to `rest` must be ARRAY, got %s",
 					args[0].Type())
 			}
 
@@ -93,14 +111,54 @@ var builtins = map[string]*object.Builtin{
 			return NULL
 		},
 	},
+	"int": &object.Builtin{
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) != 1 {
+				return newError(object.ARGUMENT_ERROR, "wrong number of arguments. got=%d, want=1",
+					len(args))
+			}
+
+			switch arg := args[0].(type) {
+			case *object.Integer, *object.BigInt:
+				return arg
+			case *object.Float:
+				if math.IsNaN(arg.Value) || math.IsInf(arg.Value, 0) {
+					return newError(object.ARITHMETIC_ERROR, "cannot convert %s to INTEGER", arg.Inspect())
+				}
+				value, _ := big.NewFloat(arg.Value).Int(nil)
+				return normalizeInteger(value)
+			default:
+				return newError(object.TYPE_ERROR, "argument to `int` not supported, got %s",
+					args[0].Type())
+			}
+		},
+	},
+	"float": &object.Builtin{
+		Fn: func(args ...object.Object) object.Object {
+			if len(args) != 1 {
+				return newError(object.ARGUMENT_ERROR, "wrong number of arguments. got=%d, want=1",
+					len(args))
+			}
+
+			switch arg := args[0].(type) {
+			case *object.Integer, *object.BigInt:
+				return &object.Float{Value: toFloat(arg)}
+			case *object.Float:
+				return arg
+			default:
+				return newError(object.TYPE_ERROR, "argument to `float` not supported, got %s",
+					args[0].Type())
+			}
+		},
+	},
 	"push": &object.Builtin{
 		Fn: func(args ...object.Object) object.Object {
 			if len(args) != 2 {
-				return newError("wrong number of arguments. got=%d, want=2",
+				return newError(object.ARGUMENT_ERROR, "wrong number of arguments. got=%d, want=2",
 					len(args))
 			}
 			if args[0].Type() != object.ARRAY_OBJ {
-				return newError("argument to `push` must be ARRAY, got %s",
+				return newError(object.TYPE_ERROR, "argument to `push` must be ARRAY, got %s",
 					args[0].Type())
 			}
 
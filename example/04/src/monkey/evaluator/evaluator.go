@@ -1,18 +1,35 @@
 package evaluator
 
 import (
+	"bytes"
 	"fmt"
+	"math"
+	"math/big"
 	"monkey/ast"
 	"monkey/object"
+	"sort"
+	"strings"
 )
 
 var (
-	NULL  = &object.Null{}
-	TRUE  = &object.Boolean{Value: true}
-	FALSE = &object.Boolean{Value: false}
+	NULL     = &object.Null{}
+	TRUE     = &object.Boolean{Value: true}
+	FALSE    = &object.Boolean{Value: false}
+	BREAK    = &object.Break{}
+	CONTINUE = &object.Continue{}
 )
 
 func Eval(node ast.Node, env *object.Environment) object.Object {
+	result := eval(node, env)
+
+	if err, ok := result.(*object.Error); ok && !err.Pos.IsValid() {
+		err.Pos = node.Pos()
+	}
+
+	return result
+}
+
+func eval(node ast.Node, env *object.Environment) object.Object {
 	switch node := node.(type) {
 
 	// Statements
@@ -32,20 +49,50 @@ func Eval(node ast.Node, env *object.Environment) object.Object {
 		}
 		return &object.ReturnValue{Value: val}
 
+	case *ast.WhileStatement:
+		return evalWhileStatement(node, env)
+
+	case *ast.ForStatement:
+		return evalForStatement(node, env)
+
+	case *ast.BreakStatement:
+		return BREAK
+
+	case *ast.ContinueStatement:
+		return CONTINUE
+
 	case *ast.LetStatement:
 		val := Eval(node.Value, env)
 		if isError(val) {
 			return val
 		}
+		if node.Pattern != nil {
+			if mismatch := matchPattern(node.Pattern, val, env); mismatch != "" {
+				return newError(object.MATCH_ERROR, "cannot destructure %s: %s", val.Inspect(), mismatch)
+			}
+			break
+		}
 		env.Set(node.Name.Value, val)
 
 	// Expressions
 	case *ast.IntegerLiteral:
+		if node.Big != nil {
+			if env.Options().CheckedArithmetic {
+				return newError(object.ARITHMETIC_ERROR, "integer overflow: %s", node.Big)
+			}
+			return &object.BigInt{Value: node.Big}
+		}
 		return &object.Integer{Value: node.Value}
 
+	case *ast.FloatLiteral:
+		return &object.Float{Value: node.Value}
+
 	case *ast.StringLiteral:
 		return &object.String{Value: node.Value}
 
+	case *ast.TemplateLiteral:
+		return evalTemplateLiteral(node, env)
+
 	case *ast.Boolean:
 		return nativeBoolToBooleanObject(node.Value)
 
@@ -54,9 +101,13 @@ func Eval(node ast.Node, env *object.Environment) object.Object {
 		if isError(right) {
 			return right
 		}
-		return evalPrefixExpression(node.Operator, right)
+		return evalPrefixExpression(node.Operator, right, env)
 
 	case *ast.InfixExpression:
+		if node.Operator == "&&" || node.Operator == "||" {
+			return evalLogicalExpression(node, env)
+		}
+
 		left := Eval(node.Left, env)
 		if isError(left) {
 			return left
@@ -67,20 +118,65 @@ func Eval(node ast.Node, env *object.Environment) object.Object {
 			return right
 		}
 
-		return evalInfixExpression(node.Operator, left, right)
+		return evalInfixExpression(node.Operator, left, right, env)
+
+	case *ast.AssignExpression:
+		return evalAssignExpression(node, env)
 
 	case *ast.IfExpression:
 		return evalIfExpression(node, env)
 
+	case *ast.MatchExpression:
+		return evalMatchExpression(node, env)
+
+	case *ast.TryExpression:
+		return evalTryExpression(node, env)
+
+	case *ast.ExportStatement:
+		return Eval(node.Statement, env)
+
+	case *ast.ImportExpression:
+		return evalImportExpression(node, env)
+
+	case *ast.ThrowStatement:
+		val := Eval(node.Value, env)
+		if isError(val) {
+			return val
+		}
+		return &object.Error{
+			Kind:    object.THROWN_ERROR,
+			Message: "uncaught exception: " + val.Inspect(),
+			Value:   val,
+		}
+
 	case *ast.Identifier:
 		return evalIdentifier(node, env)
 
 	case *ast.FunctionLiteral:
 		params := node.Parameters
 		body := node.Body
-		return &object.Function{Parameters: params, Env: env, Body: body}
+		return &object.Function{
+			Parameters: params,
+			Defaults:   node.Defaults,
+			Rest:       node.Rest,
+			Env:        env,
+			Body:       body,
+			Name:       node.Name,
+		}
+
+	case *ast.FunctionStatement:
+		// already defined by hoistFunctions when its block was entered
+		return nil
 
 	case *ast.CallExpression:
+		if node.Function.TokenLiteral() == "quote" {
+			if len(node.Arguments) != 1 {
+				return newError(object.ARGUMENT_ERROR,
+					"wrong number of arguments. got=%d, want=1", len(node.Arguments))
+			}
+			return quote(node.Arguments[0], env)
+		}
+
 		function := Eval(node.Function, env)
 		if isError(function) {
 			return function
@@ -91,7 +187,31 @@ func Eval(node ast.Node, env *object.Environment) object.Object {
 			return args[0]
 		}
 
-		return applyFunction(function, args)
+		keywords := make(map[string]object.Object)
+		for _, k := range node.Keywords {
+			val := Eval(k.Value, env)
+			if isError(val) {
+				return val
+			}
+			keywords[k.Name.Value] = val
+		}
+
+		observer := env.Options().Observer
+		if observer != nil {
+			observer.EnterCall(node, function)
+		}
+		result := applyFunction(function, args, keywords)
+		if observer != nil {
+			observer.ExitCall(node, function)
+		}
+
+		if err, ok := result.(*object.Error); ok {
+			if fn, ok := function.(*object.Function); ok {
+				err.Trace = append(err.Trace,
+					object.TraceFrame{Function: fn.Name, Pos: node.Pos()})
+			}
+		}
+		return result
 
 	case *ast.ArrayLiteral:
 		elements := evalExpressions(node.Elements, env)
@@ -122,6 +242,8 @@ func Eval(node ast.Node, env *object.Environment) object.Object {
 func evalProgram(program *ast.Program, env *object.Environment) object.Object {
 	var result object.Object
 
+	hoistFunctions(program.Statements, env)
+
 	for _, statement := range program.Statements {
 		result = Eval(statement, env)
 
@@ -142,12 +264,15 @@ func evalBlockStatement(
 ) object.Object {
 	var result object.Object
 
+	hoistFunctions(block.Statements, env)
+
 	for _, statement := range block.Statements {
 		result = Eval(statement, env)
 
 		if result != nil {
 			rt := result.Type()
-			if rt == object.RETURN_VALUE_OBJ || rt == object.ERROR_OBJ {
+			if rt == object.RETURN_VALUE_OBJ || rt == object.ERROR_OBJ ||
+				rt == object.BREAK_OBJ || rt == object.CONTINUE_OBJ {
 				return result
 			}
 		}
@@ -156,6 +281,95 @@ func evalBlockStatement(
 	return result
 }
 
+// hoistFunctions defines the functions declared by statements before any of
+// them run, so that declarations can refer to each other in any order.
+func hoistFunctions(statements []ast.Statement, env *object.Environment) {
+	for _, statement := range statements {
+		if es, ok := statement.(*ast.ExportStatement); ok {
+			statement = es.Statement
+		}
+		if fs, ok := statement.(*ast.FunctionStatement); ok {
+			env.Set(fs.Name.Value, Eval(fs.Function, env))
+		}
+	}
+}
+
+func evalWhileStatement(
+	ws *ast.WhileStatement,
+	env *object.Environment,
+) object.Object {
+	for {
+		condition := Eval(ws.Condition, env)
+		if isError(condition) {
+			return condition
+		}
+		if !isTruthy(condition) {
+			return NULL
+		}
+
+		result := Eval(ws.Body, env)
+		if stop, result := loopResult(result); stop {
+			return result
+		}
+	}
+}
+
+func evalForStatement(
+	fs *ast.ForStatement,
+	env *object.Environment,
+) object.Object {
+	iterable := Eval(fs.Iterable, env)
+	if isError(iterable) {
+		return iterable
+	}
+
+	var items []object.Object
+
+	switch iterable := iterable.(type) {
+	case *object.Array:
+		items = iterable.Elements
+	case *object.Hash:
+		for _, pair := range iterable.OrderedPairs() {
+			items = append(items, pair.Key)
+		}
+	case *object.String:
+		for _, ch := range iterable.Value {
+			items = append(items, &object.String{Value: string(ch)})
+		}
+	default:
+		return newError(object.TYPE_ERROR, "cannot iterate over %s", iterable.Type())
+	}
+
+	for _, item := range items {
+		loopEnv := object.NewEnclosedEnvironment(env)
+		loopEnv.Set(fs.Variable.Value, item)
+
+		result := Eval(fs.Body, loopEnv)
+		if stop, result := loopResult(result); stop {
+			return result
+		}
+	}
+
+	return NULL
+}
+
+// loopResult interprets the result of a loop body and reports whether the
+// loop has to stop and what it then evaluates to.
+func loopResult(result object.Object) (bool, object.Object) {
+	if result == nil {
+		return false, nil
+	}
+
+	switch result.Type() {
+	case object.BREAK_OBJ:
+		return true, NULL
+	case object.RETURN_VALUE_OBJ, object.ERROR_OBJ:
+		return true, result
+	default:
+		return false, nil
+	}
+}
+
 func nativeBoolToBooleanObject(input bool) *object.Boolean {
 	if input {
 		return TRUE
@@ -163,24 +377,35 @@ func nativeBoolToBooleanObject(input bool) *object.Boolean {
 	return FALSE
 }
 
-func evalPrefixExpression(operator string, right object.Object) object.Object {
+func evalPrefixExpression(
+	operator string,
+	right object.Object,
+	env *object.Environment,
+) object.Object {
 	switch operator {
 	case "!":
 		return evalBangOperatorExpression(right)
 	case "-":
-		return evalMinusPrefixOperatorExpression(right)
+		return evalMinusPrefixOperatorExpression(right, env)
+	case "~":
+		return evalBitNotPrefixOperatorExpression(right)
 	default:
-		return newError("unknown operator: %s%s", operator, right.Type())
+		return newError(object.TYPE_ERROR, "unknown operator: %s%s", operator, right.Type())
 	}
 }
 
 func evalInfixExpression(
 	operator string,
 	left, right object.Object,
+	env *object.Environment,
 ) object.Object {
 	switch {
 	case left.Type() == object.INTEGER_OBJ && right.Type() == object.INTEGER_OBJ:
-		return evalIntegerInfixExpression(operator, left, right)
+		return evalIntegerInfixExpression(operator, left, right, env)
+	case isIntegral(left) && isIntegral(right):
+		return evalBigIntInfixExpression(operator, left, right)
+	case isNumber(left) && isNumber(right):
+		return evalFloatInfixExpression(operator, left, right)
 	case left.Type() == object.STRING_OBJ && right.Type() == object.STRING_OBJ:
 		return evalStringInfixExpression(operator, left, right)
 	case operator == "==":
@@ -188,14 +413,40 @@ func evalInfixExpression(
 	case operator == "!=":
 		return nativeBoolToBooleanObject(left != right)
 	case left.Type() != right.Type():
-		return newError("type mismatch: %s %s %s",
+		return newError(object.TYPE_ERROR, "type mismatch: %s %s %s",
 			left.Type(), operator, right.Type())
 	default:
-		return newError("unknown operator: %s %s %s",
+		return newError(object.TYPE_ERROR, "unknown operator: %s %s %s",
 			left.Type(), operator, right.Type())
 	}
 }
 
+// evalLogicalExpression evaluates && and ||. The right operand is only
+// evaluated when the left one does not already decide the result.
+func evalLogicalExpression(
+	node *ast.InfixExpression,
+	env *object.Environment,
+) object.Object {
+	left := Eval(node.Left, env)
+	if isError(left) {
+		return left
+	}
+
+	if node.Operator == "&&" && !isTruthy(left) {
+		return FALSE
+	}
+	if node.Operator == "||" && isTruthy(left) {
+		return TRUE
+	}
+
+	right := Eval(node.Right, env)
+	if isError(right) {
+		return right
+	}
+
+	return nativeBoolToBooleanObject(isTruthy(right))
+}
+
 func evalBangOperatorExpression(right object.Object) object.Object {
 	switch right {
 	case TRUE:
@@ -209,57 +460,430 @@ func evalBangOperatorExpression(right object.Object) object.Object {
 	}
 }
 
-func evalMinusPrefixOperatorExpression(right object.Object) object.Object {
-	if right.Type() != object.INTEGER_OBJ {
-		return newError("unknown operator: -%s", right.Type())
+func evalMinusPrefixOperatorExpression(
+	right object.Object,
+	env *object.Environment,
+) object.Object {
+	switch right := right.(type) {
+	case *object.Integer:
+		if right.Value == math.MinInt64 {
+			if env.Options().CheckedArithmetic {
+				return newError(object.ARITHMETIC_ERROR, "integer overflow: -%d", right.Value)
+			}
+			return normalizeInteger(new(big.Int).Neg(big.NewInt(right.Value)))
+		}
+		return &object.Integer{Value: -right.Value}
+	case *object.BigInt:
+		return normalizeInteger(new(big.Int).Neg(right.Value))
+	case *object.Float:
+		return &object.Float{Value: -right.Value}
+	default:
+		return newError(object.TYPE_ERROR, "unknown operator: -%s", right.Type())
 	}
+}
 
-	value := right.(*object.Integer).Value
-	return &object.Integer{Value: -value}
+func evalBitNotPrefixOperatorExpression(right object.Object) object.Object {
+	switch right := right.(type) {
+	case *object.Integer:
+		return &object.Integer{Value: ^right.Value}
+	case *object.BigInt:
+		return normalizeInteger(new(big.Int).Not(right.Value))
+	default:
+		return newError(object.TYPE_ERROR, "unknown operator: ~%s", right.Type())
+	}
 }
 
 func evalIntegerInfixExpression(
 	operator string,
 	left, right object.Object,
+	env *object.Environment,
 ) object.Object {
 	leftVal := left.(*object.Integer).Value
 	rightVal := right.(*object.Integer).Value
 
+	switch operator {
+	case "+", "-", "*", "/", "%", "<<":
+		if (operator == "/" || operator == "%") && rightVal == 0 {
+			return newError(object.ARITHMETIC_ERROR, "division by zero")
+		}
+		if operator == "<<" && rightVal < 0 {
+			return newError(object.ARITHMETIC_ERROR, "negative shift count: %d", rightVal)
+		}
+		result, overflow := integerArithmetic(operator, leftVal, rightVal)
+		if overflow {
+			if env.Options().CheckedArithmetic {
+				return newError(object.ARITHMETIC_ERROR, "integer overflow: %d %s %d",
+					leftVal, operator, rightVal)
+			}
+			return evalBigIntInfixExpression(operator, left, right)
+		}
+		return &object.Integer{Value: result}
+	case ">>":
+		if rightVal < 0 {
+			return newError(object.ARITHMETIC_ERROR, "negative shift count: %d", rightVal)
+		}
+		return &object.Integer{Value: leftVal >> uint64(rightVal)}
+	case "&":
+		return &object.Integer{Value: leftVal & rightVal}
+	case "|":
+		return &object.Integer{Value: leftVal | rightVal}
+	case "^":
+		return &object.Integer{Value: leftVal ^ rightVal}
+	case "<":
+		return nativeBoolToBooleanObject(leftVal < rightVal)
+	case ">":
+		return nativeBoolToBooleanObject(leftVal > rightVal)
+	case "<=":
+		return nativeBoolToBooleanObject(leftVal <= rightVal)
+	case ">=":
+		return nativeBoolToBooleanObject(leftVal >= rightVal)
+	case "==":
+		return nativeBoolToBooleanObject(leftVal == rightVal)
+	case "!=":
+		return nativeBoolToBooleanObject(leftVal != rightVal)
+	default:
+		return newError(object.TYPE_ERROR, "unknown operator: %s %s %s",
+			left.Type(), operator, right.Type())
+	}
+}
+
+// integerArithmetic returns the wrapped-around result of applying operator to
+// left and right and whether that result overflowed.
+func integerArithmetic(operator string, left, right int64) (int64, bool) {
 	switch operator {
 	case "+":
-		return &object.Integer{Value: leftVal + rightVal}
+		result := left + right
+		return result, (left >= 0) == (right >= 0) && (result >= 0) != (left >= 0)
 	case "-":
-		return &object.Integer{Value: leftVal - rightVal}
+		result := left - right
+		return result, (left >= 0) != (right >= 0) && (result >= 0) != (left >= 0)
 	case "*":
-		return &object.Integer{Value: leftVal * rightVal}
+		result := left * right
+		overflow := left != 0 && (result/left != right ||
+			left == -1 && right == math.MinInt64)
+		return result, overflow
 	case "/":
-		return &object.Integer{Value: leftVal / rightVal}
+		return left / right, left == math.MinInt64 && right == -1
+	case "%":
+		return left % right, false
+	case "<<":
+		if right >= 64 {
+			return 0, left != 0
+		}
+		result := left << uint64(right)
+		return result, result>>uint64(right) != left
+	default:
+		return 0, false
+	}
+}
+
+func evalBigIntInfixExpression(
+	operator string,
+	left, right object.Object,
+) object.Object {
+	leftVal := toBigInt(left)
+	rightVal := toBigInt(right)
+
+	switch operator {
+	case "+":
+		return normalizeInteger(new(big.Int).Add(leftVal, rightVal))
+	case "-":
+		return normalizeInteger(new(big.Int).Sub(leftVal, rightVal))
+	case "*":
+		return normalizeInteger(new(big.Int).Mul(leftVal, rightVal))
+	case "/":
+		if rightVal.Sign() == 0 {
+			return newError(object.ARITHMETIC_ERROR, "division by zero")
+		}
+		return normalizeInteger(new(big.Int).Quo(leftVal, rightVal))
+	case "%":
+		if rightVal.Sign() == 0 {
+			return newError(object.ARITHMETIC_ERROR, "division by zero")
+		}
+		return normalizeInteger(new(big.Int).Rem(leftVal, rightVal))
+	case "<<", ">>":
+		if !rightVal.IsUint64() || rightVal.Uint64() > math.MaxUint32 {
+			return newError(object.ARITHMETIC_ERROR, "invalid shift count: %s", rightVal)
+		}
+		if operator == "<<" {
+			return normalizeInteger(new(big.Int).Lsh(leftVal, uint(rightVal.Uint64())))
+		}
+		return normalizeInteger(new(big.Int).Rsh(leftVal, uint(rightVal.Uint64())))
+	case "&":
+		return normalizeInteger(new(big.Int).And(leftVal, rightVal))
+	case "|":
+		return normalizeInteger(new(big.Int).Or(leftVal, rightVal))
+	case "^":
+		return normalizeInteger(new(big.Int).Xor(leftVal, rightVal))
+	case "<":
+		return nativeBoolToBooleanObject(leftVal.Cmp(rightVal) < 0)
+	case ">":
+		return nativeBoolToBooleanObject(leftVal.Cmp(rightVal) > 0)
+	case "<=":
+		return nativeBoolToBooleanObject(leftVal.Cmp(rightVal) <= 0)
+	case ">=":
+		return nativeBoolToBooleanObject(leftVal.Cmp(rightVal) >= 0)
+	case "==":
+		return nativeBoolToBooleanObject(leftVal.Cmp(rightVal) == 0)
+	case "!=":
+		return nativeBoolToBooleanObject(leftVal.Cmp(rightVal) != 0)
+	default:
+		return newError(object.TYPE_ERROR, "unknown operator: %s %s %s",
+			left.Type(), operator, right.Type())
+	}
+}
+
+// normalizeInteger returns value as an Integer when it fits into an int64 and
+// as a BigInt otherwise.
+func normalizeInteger(value *big.Int) object.Object {
+	if value.IsInt64() {
+		return &object.Integer{Value: value.Int64()}
+	}
+	return &object.BigInt{Value: value}
+}
+
+func isIntegral(obj object.Object) bool {
+	return obj.Type() == object.INTEGER_OBJ || obj.Type() == object.BIGINT_OBJ
+}
+
+func toBigInt(obj object.Object) *big.Int {
+	switch obj := obj.(type) {
+	case *object.Integer:
+		return big.NewInt(obj.Value)
+	case *object.BigInt:
+		return obj.Value
+	default:
+		return new(big.Int)
+	}
+}
+
+func evalFloatInfixExpression(
+	operator string,
+	left, right object.Object,
+) object.Object {
+	leftVal := toFloat(left)
+	rightVal := toFloat(right)
+
+	switch operator {
+	case "+":
+		return &object.Float{Value: leftVal + rightVal}
+	case "-":
+		return &object.Float{Value: leftVal - rightVal}
+	case "*":
+		return &object.Float{Value: leftVal * rightVal}
+	case "/":
+		return &object.Float{Value: leftVal / rightVal}
+	case "%":
+		return &object.Float{Value: math.Mod(leftVal, rightVal)}
 	case "<":
 		return nativeBoolToBooleanObject(leftVal < rightVal)
 	case ">":
 		return nativeBoolToBooleanObject(leftVal > rightVal)
+	case "<=":
+		return nativeBoolToBooleanObject(leftVal <= rightVal)
+	case ">=":
+		return nativeBoolToBooleanObject(leftVal >= rightVal)
 	case "==":
 		return nativeBoolToBooleanObject(leftVal == rightVal)
 	case "!=":
 		return nativeBoolToBooleanObject(leftVal != rightVal)
 	default:
-		return newError("unknown operator: %s %s %s",
+		return newError(object.TYPE_ERROR, "unknown operator: %s %s %s",
 			left.Type(), operator, right.Type())
 	}
 }
 
+func isNumber(obj object.Object) bool {
+	return isIntegral(obj) || obj.Type() == object.FLOAT_OBJ
+}
+
+func toFloat(obj object.Object) float64 {
+	switch obj := obj.(type) {
+	case *object.Integer:
+		return float64(obj.Value)
+	case *object.BigInt:
+		f, _ := new(big.Float).SetInt(obj.Value).Float64()
+		return f
+	case *object.Float:
+		return obj.Value
+	default:
+		return 0
+	}
+}
+
 func evalStringInfixExpression(
 	operator string,
 	left, right object.Object,
 ) object.Object {
-	if operator != "+" {
-		return newError("unknown operator: %s %s %s",
+	leftVal := left.(*object.String).Value
+	rightVal := right.(*object.String).Value
+
+	switch operator {
+	case "+":
+		return &object.String{Value: leftVal + rightVal}
+	case "<":
+		return nativeBoolToBooleanObject(leftVal < rightVal)
+	case ">":
+		return nativeBoolToBooleanObject(leftVal > rightVal)
+	case "<=":
+		return nativeBoolToBooleanObject(leftVal <= rightVal)
+	case ">=":
+		return nativeBoolToBooleanObject(leftVal >= rightVal)
+	case "==":
+		return nativeBoolToBooleanObject(leftVal == rightVal)
+	case "!=":
+		return nativeBoolToBooleanObject(leftVal != rightVal)
+	default:
+		return newError(object.TYPE_ERROR, "unknown operator: %s %s %s",
 			left.Type(), operator, right.Type())
 	}
+}
 
-	leftVal := left.(*object.String).Value
-	rightVal := right.(*object.String).Value
-	return &object.String{Value: leftVal + rightVal}
+func evalAssignExpression(
+	node *ast.AssignExpression,
+	env *object.Environment,
+) object.Object {
+	switch target := node.Target.(type) {
+	case *ast.Identifier:
+		return evalIdentifierAssignment(node, target, env)
+	case *ast.IndexExpression:
+		return evalIndexAssignment(node, target, env)
+	default:
+		return newError(object.TYPE_ERROR, "cannot assign to %s", node.Target.String())
+	}
+}
+
+func evalIdentifierAssignment(
+	node *ast.AssignExpression,
+	ident *ast.Identifier,
+	env *object.Environment,
+) object.Object {
+	var current object.Object
+	if node.Operator != "=" {
+		var ok bool
+		if current, ok = env.Get(ident.Value); !ok {
+			return newError(object.NAME_ERROR, "assignment to undeclared identifier: %s", ident.Value)
+		}
+	}
+
+	val := evalAssignedValue(node, current, env)
+	if isError(val) {
+		return val
+	}
+
+	if !env.Assign(ident.Value, val) {
+		return newError(object.NAME_ERROR, "assignment to undeclared identifier: %s", ident.Value)
+	}
+
+	return val
+}
+
+func evalIndexAssignment(
+	node *ast.AssignExpression,
+	target *ast.IndexExpression,
+	env *object.Environment,
+) object.Object {
+	left := Eval(target.Left, env)
+	if isError(left) {
+		return left
+	}
+
+	index := Eval(target.Index, env)
+	if isError(index) {
+		return index
+	}
+
+	switch left := left.(type) {
+	case *object.Array:
+		idx, ok := index.(*object.Integer)
+		if !ok {
+			return newError(object.TYPE_ERROR, "array index must be INTEGER, got %s", index.Type())
+		}
+		if idx.Value < 0 || idx.Value >= int64(len(left.Elements)) {
+			return newError(object.INDEX_ERROR, "index out of range: %d (length %d)",
+				idx.Value, len(left.Elements))
+		}
+
+		var current object.Object
+		if node.Operator != "=" {
+			current = left.Elements[idx.Value]
+		}
+
+		val := evalAssignedValue(node, current, env)
+		if isError(val) {
+			return val
+		}
+
+		left.Elements[idx.Value] = val
+		return val
+
+	case *object.Hash:
+		key, ok := index.(object.Hashable)
+		if !ok {
+			return newError(object.TYPE_ERROR, "unusable as hash key: %s", index.Type())
+		}
+		hashed := key.HashKey()
+
+		var current object.Object
+		if node.Operator != "=" {
+			pair, ok := left.Pairs[hashed]
+			if !ok {
+				return newError(object.INDEX_ERROR, "key not found: %s", index.Inspect())
+			}
+			current = pair.Value
+		}
+
+		val := evalAssignedValue(node, current, env)
+		if isError(val) {
+			return val
+		}
+
+		left.Set(hashed, object.HashPair{Key: index, Value: val})
+		return val
+
+	default:
+		return newError(object.TYPE_ERROR, "index assignment not supported: %s", left.Type())
+	}
+}
+
+// evalAssignedValue evaluates the right-hand side of an assignment. For
+// compound operators such as += it is combined with the current value.
+func evalAssignedValue(
+	node *ast.AssignExpression,
+	current object.Object,
+	env *object.Environment,
+) object.Object {
+	val := Eval(node.Value, env)
+	if isError(val) {
+		return val
+	}
+
+	if current == nil {
+		return val
+	}
+
+	operator := strings.TrimSuffix(node.Operator, "=")
+	return evalInfixExpression(operator, current, val, env)
+}
+
+func evalTemplateLiteral(
+	tl *ast.TemplateLiteral,
+	env *object.Environment,
+) object.Object {
+	var out bytes.Buffer
+
+	for _, part := range tl.Parts {
+		evaluated := Eval(part, env)
+		if isError(evaluated) {
+			return evaluated
+		}
+		if evaluated == nil {
+			evaluated = NULL
+		}
+		out.WriteString(evaluated.Inspect())
+	}
+
+	return &object.String{Value: out.String()}
 }
 
 func evalIfExpression(
@@ -280,6 +904,155 @@ func evalIfExpression(
 	}
 }
 
+func evalTryExpression(
+	te *ast.TryExpression,
+	env *object.Environment,
+) object.Object {
+	result := Eval(te.Block, env)
+
+	if err, ok := result.(*object.Error); ok && te.Catch != nil {
+		catchEnv := object.NewEnclosedEnvironment(env)
+		if te.Param != nil {
+			catchEnv.Set(te.Param.Value, caughtValue(err))
+		}
+		result = Eval(te.Catch, catchEnv)
+	}
+
+	if te.Finally != nil {
+		// an error, return or loop control in the finally block replaces
+		// whatever the try or catch block produced
+		finally := Eval(te.Finally, env)
+		if finally != nil {
+			switch finally.Type() {
+			case object.ERROR_OBJ, object.RETURN_VALUE_OBJ,
+				object.BREAK_OBJ, object.CONTINUE_OBJ:
+				return finally
+			}
+		}
+	}
+
+	return result
+}
+
+// caughtValue is what a catch block sees for err: the value itself for
+// thrown values, otherwise a hash with the error's kind and message.
+func caughtValue(err *object.Error) object.Object {
+	if err.Value != nil {
+		return err.Value
+	}
+
+	hash := &object.Hash{}
+	for _, field := range [][2]string{
+		{"kind", err.Kind},
+		{"message", err.Message},
+	} {
+		key := &object.String{Value: field[0]}
+		hash.Set(key.HashKey(), object.HashPair{Key: key, Value: &object.String{Value: field[1]}})
+	}
+
+	return hash
+}
+
+func evalMatchExpression(
+	me *ast.MatchExpression,
+	env *object.Environment,
+) object.Object {
+	subject := Eval(me.Subject, env)
+	if isError(subject) {
+		return subject
+	}
+
+	for _, arm := range me.Arms {
+		armEnv := object.NewEnclosedEnvironment(env)
+		if matchPattern(arm.Pattern, subject, armEnv) == "" {
+			return Eval(arm.Body, armEnv)
+		}
+	}
+
+	return newError(object.MATCH_ERROR, "no match arm for %s", subject.Inspect())
+}
+
+// matchPattern binds the identifiers of pattern in env and returns "" if
+// val matches it, or otherwise a description of the first mismatch.
+func matchPattern(
+	pattern ast.Pattern,
+	val object.Object,
+	env *object.Environment,
+) string {
+	switch pattern := pattern.(type) {
+	case *ast.Identifier:
+		if pattern.Value != "_" {
+			env.Set(pattern.Value, val)
+		}
+		return ""
+
+	case *ast.LiteralPattern:
+		literal := Eval(pattern.Value, env)
+		if evalInfixExpression("==", literal, val, env) != TRUE {
+			return fmt.Sprintf("expected %s, got %s", literal.Inspect(), val.Inspect())
+		}
+		return ""
+
+	case *ast.ArrayPattern:
+		array, ok := val.(*object.Array)
+		if !ok {
+			return fmt.Sprintf("expected ARRAY, got %s", val.Type())
+		}
+
+		n := len(pattern.Elements)
+		if pattern.Rest == nil && len(array.Elements) != n {
+			return fmt.Sprintf("expected %d elements, got %d", n, len(array.Elements))
+		}
+		if len(array.Elements) < n {
+			return fmt.Sprintf("expected at least %d elements, got %d",
+				n, len(array.Elements))
+		}
+
+		for i, element := range pattern.Elements {
+			if mismatch := matchPattern(element, array.Elements[i], env); mismatch != "" {
+				return mismatch
+			}
+		}
+
+		if pattern.Rest != nil {
+			rest := make([]object.Object, len(array.Elements)-n)
+			copy(rest, array.Elements[n:])
+			matchPattern(pattern.Rest, &object.Array{Elements: rest}, env)
+		}
+		return ""
+
+	case *ast.HashPattern:
+		if module, ok := val.(*object.Module); ok {
+			val = moduleHash(module)
+		}
+
+		hash, ok := val.(*object.Hash)
+		if !ok {
+			return fmt.Sprintf("expected HASH, got %s", val.Type())
+		}
+
+		for _, pair := range pattern.Pairs {
+			key := Eval(pair.Key, env)
+			hashKey, ok := key.(object.Hashable)
+			if !ok {
+				return fmt.Sprintf("unusable as hash key: %s", key.Type())
+			}
+
+			found, ok := hash.Pairs[hashKey.HashKey()]
+			if !ok {
+				return fmt.Sprintf("missing key %s", key.Inspect())
+			}
+			if mismatch := matchPattern(pair.Value, found.Value, env); mismatch != "" {
+				return mismatch
+			}
+		}
+		return ""
+
+	default:
+		return fmt.Sprintf("unsupported pattern %s", pattern.String())
+	}
+}
+
 func evalIdentifier(
 	node *ast.Identifier,
 	env *object.Environment,
@@ -292,7 +1065,7 @@ func evalIdentifier(
 		return builtin
 	}
 
-	return newError("identifier not found: " + node.Value)
+	return newError(object.NAME_ERROR, "identifier not found: "+node.Value)
 }
 
 func isTruthy(obj object.Object) bool {
@@ -308,8 +1081,8 @@ func isTruthy(obj object.Object) bool {
 	}
 }
 
-func newError(format string, a ...interface{}) *object.Error {
-	return &object.Error{Message: fmt.Sprintf(format, a...)}
+func newError(kind string, format string, a ...interface{}) *object.Error {
+	return &object.Error{Kind: kind, Message: fmt.Sprintf(format, a...)}
 }
 
 func isError(obj object.Object) bool {
@@ -336,33 +1109,129 @@ func evalExpressions(
 	return result
 }
 
-func applyFunction(fn object.Object, args []object.Object) object.Object {
+func applyFunction(
+	fn object.Object,
+	args []object.Object,
+	keywords map[string]object.Object,
+) object.Object {
 	switch fn := fn.(type) {
 
 	case *object.Function:
-		extendedEnv := extendFunctionEnv(fn, args)
+		extendedEnv, err := extendFunctionEnv(fn, args, keywords)
+		if err != nil {
+			return err
+		}
 		evaluated := Eval(fn.Body, extendedEnv)
 		return unwrapReturnValue(evaluated)
 
 	case *object.Builtin:
+		if len(keywords) > 0 {
+			return newError(object.ARGUMENT_ERROR, "builtin functions do not accept keyword arguments")
+		}
 		return fn.Fn(args...)
 
 	default:
-		return newError("not a function: %s", fn.Type())
+		return newError(object.TYPE_ERROR, "not a function: %s", fn.Type())
 	}
 }
 
+// extendFunctionEnv binds positional arguments in order, then keyword
+// arguments by name. Parameters left unbound take their default values,
+// which are evaluated in the new environment and so can refer to the
+// parameters before them.
 func extendFunctionEnv(
 	fn *object.Function,
 	args []object.Object,
-) *object.Environment {
+	keywords map[string]object.Object,
+) (*object.Environment, *object.Error) {
 	env := object.NewEnclosedEnvironment(fn.Env)
 
+	if len(args) > len(fn.Parameters) && fn.Rest == nil {
+		return nil, newError(object.ARGUMENT_ERROR, "wrong number of arguments. got=%d, want=%s",
+			len(args), arity(fn))
+	}
+
+	names := []string{}
+	for name := range keywords {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		idx := parameterIndex(fn, name)
+		if idx < 0 {
+			return nil, newError(object.ARGUMENT_ERROR, "unexpected keyword argument: %s", name)
+		}
+		if idx < len(args) {
+			return nil, newError(object.ARGUMENT_ERROR, "multiple values for argument: %s", name)
+		}
+	}
+
 	for paramIdx, param := range fn.Parameters {
-		env.Set(param.Value, args[paramIdx])
+		if paramIdx < len(args) {
+			env.Set(param.Value, args[paramIdx])
+			continue
+		}
+
+		if val, ok := keywords[param.Value]; ok {
+			env.Set(param.Value, val)
+			continue
+		}
+
+		def, ok := fn.Defaults[param.Value]
+		if !ok {
+			if len(keywords) == 0 {
+				return nil, newError(object.ARGUMENT_ERROR, "wrong number of arguments. got=%d, want=%s",
+					len(args), arity(fn))
+			}
+			return nil, newError(object.ARGUMENT_ERROR, "missing argument: %s", param.Value)
+		}
+
+		val := Eval(def, env)
+		if err, ok := val.(*object.Error); ok {
+			return nil, err
+		}
+		env.Set(param.Value, val)
+	}
+
+	if fn.Rest != nil {
+		rest := []object.Object{}
+		if len(args) > len(fn.Parameters) {
+			rest = append(rest, args[len(fn.Parameters):]...)
+		}
+		env.Set(fn.Rest.Value, &object.Array{Elements: rest})
+	}
+
+	return env, nil
+}
+
+func parameterIndex(fn *object.Function, name string) int {
+	for i, param := range fn.Parameters {
+		if param.Value == name {
+			return i
+		}
 	}
+	return -1
+}
 
-	return env
+// arity describes how many positional arguments fn accepts, e.g. "2",
+// "1..3" or "1+".
+func arity(fn *object.Function) string {
+	required := 0
+	for _, param := range fn.Parameters {
+		if _, ok := fn.Defaults[param.Value]; !ok {
+			required++
+		}
+	}
+
+	switch {
+	case fn.Rest != nil:
+		return fmt.Sprintf("%d+", required)
+	case required == len(fn.Parameters):
+		return fmt.Sprintf("%d", required)
+	default:
+		return fmt.Sprintf("%d..%d", required, len(fn.Parameters))
+	}
 }
 
 func unwrapReturnValue(obj object.Object) object.Object {
@@ -379,8 +1248,10 @@ func evalIndexExpression(left, index object.Object) object.Object {
 		return evalArrayIndexExpression(left, index)
 	case left.Type() == object.HASH_OBJ:
 		return evalHashIndexExpression(left, index)
+	case left.Type() == object.MODULE_OBJ && index.Type() == object.STRING_OBJ:
+		return evalModuleIndexExpression(left, index)
 	default:
-		return newError("index operator not supported: %s", left.Type())
+		return newError(object.TYPE_ERROR, "index operator not supported: %s", left.Type())
 	}
 }
 
@@ -400,29 +1271,28 @@ func evalHashLiteral(
 	node *ast.HashLiteral,
 	env *object.Environment,
 ) object.Object {
-	pairs := make(map[object.HashKey]object.HashPair)
+	hash := &object.Hash{Pairs: make(map[object.HashKey]object.HashPair)}
 
-	for keyNode, valueNode := range node.Pairs {
-		key := Eval(keyNode, env)
+	for _, pair := range node.Pairs {
+		key := Eval(pair.Key, env)
 		if isError(key) {
 			return key
 		}
 
 		hashKey, ok := key.(object.Hashable)
 		if !ok {
-			return newError("unusable as hash key: %s", key.Type())
+			return newError(object.TYPE_ERROR, "unusable as hash key: %s", key.Type())
 		}
 
-		value := Eval(valueNode, env)
+		value := Eval(pair.Value, env)
 		if isError(value) {
 			return value
 		}
 
-		hashed := hashKey.HashKey()
-		pairs[hashed] = object.HashPair{Key: key, Value: value}
+		hash.Set(hashKey.HashKey(), object.HashPair{Key: key, Value: value})
 	}
 
-	return &object.Hash{Pairs: pairs}
+	return hash
 }
 
 func evalHashIndexExpression(hash, index object.Object) object.Object {
@@ -430,7 +1300,7 @@ func evalHashIndexExpression(hash, index object.Object) object.Object {
 
 	key, ok := index.(object.Hashable)
 	if !ok {
-		return newError("unusable as hash key: %s", index.Type())
+		return newError(object.TYPE_ERROR, "unusable as hash key: %s", index.Type())
 	}
 
 	pair, ok := hashObject.Pairs[key.HashKey()]
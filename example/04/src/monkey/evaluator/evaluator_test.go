@@ -1,9 +1,12 @@
 package evaluator
 
 import (
+	"math"
+	"monkey/ast"
 	"monkey/lexer"
 	"monkey/object"
 	"monkey/parser"
+	"strings"
 	"testing"
 )
 
@@ -35,6 +38,194 @@ func TestEvalIntegerExpression(t *testing.T) {
 	}
 }
 
+func TestArithmeticAndBitwiseOperators(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected int64
+	}{
+		{"7 % 3", 1},
+		{"-7 % 3", -1},
+		{"2 + 10 % 4 * 3", 8},
+		{"6 & 3", 2},
+		{"6 | 3", 7},
+		{"6 ^ 3", 5},
+		{"~5", -6},
+		{"1 << 10", 1024},
+		{"1024 >> 3", 128},
+		{"-16 >> 2", -4},
+		{"1 << 2 + 1", 5},
+	}
+
+	for _, tt := range tests {
+		testIntegerObject(t, testEval(tt.input), tt.expected)
+	}
+
+	testBigIntObject(t, testEval("1 << 64"), "18446744073709551616")
+	testBigIntObject(t, testEval("(1 << 64) | 1"), "18446744073709551617")
+	testIntegerObject(t, testEval("(1 << 64) >> 60"), 16)
+	testIntegerObject(t, testEval("(1 << 64 | 5) % 8"), 5)
+	testFloatObject(t, testEval("7.5 % 2"), 1.5)
+}
+
+func TestLogicalOperators(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected bool
+	}{
+		{"true && true", true},
+		{"true && false", false},
+		{"false || true", true},
+		{"false || false", false},
+		{"1 < 2 && 2 < 3", true},
+		{"1 > 2 || 2 > 3", false},
+		{"1 && 0", true},
+		{`"" || false`, true},
+		{"!true || !false && true", true},
+		// the right operand must not be evaluated when the left decides
+		{"false && missing", false},
+		{"true || missing", true},
+		{"false && 1 / 0", false},
+		{`
+let f = fn() { puts("called"); true };
+false && f()`, false},
+	}
+
+	for _, tt := range tests {
+		testBooleanObject(t, testEval(tt.input), tt.expected)
+	}
+
+	evaluated := testEval("true && missing")
+	errObj, ok := evaluated.(*object.Error)
+	if !ok {
+		t.Fatalf("no error object returned. got=%T(%+v)", evaluated, evaluated)
+	}
+	if errObj.Message != "identifier not found: missing" {
+		t.Errorf("wrong error message. got=%q", errObj.Message)
+	}
+}
+
+func TestBigIntPromotion(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"9223372036854775807 + 1", "9223372036854775808"},
+		{"-9223372036854775807 - 2", "-9223372036854775809"},
+		{"4611686018427387904 * 2", "9223372036854775808"},
+		{"let min = -9223372036854775807 - 1; -min", "9223372036854775808"},
+		{"let min = -9223372036854775807 - 1; min / -1", "9223372036854775808"},
+		{"9223372036854775807 * 9223372036854775807", "85070591730234615847396907784232501249"},
+		{`
+let factorial = fn(n) { if (n < 2) { 1 } else { n * factorial(n - 1) } };
+factorial(30)`, "265252859812191058636308480000000"},
+		{"100000000000000000000", "100000000000000000000"},
+		{"-100_000_000_000_000_000_000", "-100000000000000000000"},
+		{"0xffff_ffff_ffff_ffff + 1", "18446744073709551616"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		testBigIntObject(t, evaluated, tt.expected)
+	}
+
+	demoted := []struct {
+		input    string
+		expected int64
+	}{
+		{"-9223372036854775808", -9223372036854775808},
+		{"(9223372036854775807 + 1) - 1", math.MaxInt64},
+		{"(9223372036854775807 * 4) / 4", math.MaxInt64},
+		{"-(-9223372036854775807 - 2) - 2", math.MaxInt64},
+		{"int(1e18)", 1000000000000000000},
+	}
+
+	for _, tt := range demoted {
+		testIntegerObject(t, testEval(tt.input), tt.expected)
+	}
+
+	booleans := []struct {
+		input    string
+		expected bool
+	}{
+		{"9223372036854775807 + 1 > 9223372036854775807", true},
+		{"9223372036854775807 + 1 == 9223372036854775807 + 1", true},
+		{"-9223372036854775807 - 2 < 0", true},
+		{"9223372036854775807 * 2 > 1.5", true},
+		{"float(9223372036854775807 * 2) == 18446744073709551614.0", true},
+	}
+
+	for _, tt := range booleans {
+		testBooleanObject(t, testEval(tt.input), tt.expected)
+	}
+
+	testBigIntObject(t, testEval("int(1e30)"), "1000000000000000019884624838656")
+	testIntegerObject(t, testEval(`
+let big = 9223372036854775807 * 3;
+{big: 1}[9223372036854775807 * 3]`), 1)
+	testIntegerObject(t, testEval("{1e20: 1}[100000000000000000000]"), 1)
+}
+
+func TestIntegerOverflow(t *testing.T) {
+	options := object.Options{CheckedArithmetic: true}
+
+	checked := []struct {
+		input    string
+		expected string
+	}{
+		{"9223372036854775807 + 1", "integer overflow: 9223372036854775807 + 1"},
+		{"-9223372036854775807 - 2", "integer overflow: -9223372036854775807 - 2"},
+		{"4611686018427387904 * 2", "integer overflow: 4611686018427387904 * 2"},
+		{"let min = -9223372036854775807 - 1; min / -1", "integer overflow: -9223372036854775808 / -1"},
+		{"let min = -9223372036854775807 - 1; -min", "integer overflow: --9223372036854775808"},
+		{"-1 * (-9223372036854775807 - 1)", "integer overflow: -1 * -9223372036854775808"},
+		{"100000000000000000000", "integer overflow: 100000000000000000000"},
+		{"let f = fn(x) { x + 1 }; f(9223372036854775807)", "integer overflow: 9223372036854775807 + 1"},
+		{"let x = 9223372036854775807; x += 1", "integer overflow: 9223372036854775807 + 1"},
+	}
+
+	for _, tt := range checked {
+		evaluated := testEvalWithOptions(tt.input, options)
+
+		errObj, ok := evaluated.(*object.Error)
+		if !ok {
+			t.Errorf("no error object returned for %q. got=%T(%+v)",
+				tt.input, evaluated, evaluated)
+			continue
+		}
+
+		if errObj.Message != tt.expected {
+			t.Errorf("wrong error message. expected=%q, got=%q",
+				tt.expected, errObj.Message)
+		}
+	}
+
+	testIntegerObject(t, testEvalWithOptions("4611686018427387903 * 2 + 1", options), math.MaxInt64)
+	testBigIntObject(t, testEval("9223372036854775807 + 1"), "9223372036854775808")
+}
+
+func TestEvalFloatExpression(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected float64
+	}{
+		{"2.5", 2.5},
+		{"-2.5", -2.5},
+		{"1e3", 1000},
+		{"1.5 + 1.5", 3},
+		{"1 + 0.5", 1.5},
+		{"0.5 + 1", 1.5},
+		{"10 * 0.1", 1},
+		{"7 / 2.0", 3.5},
+		{"(1 + 2 + 3 + 4) / 4.0", 2.5},
+		{"3.0 - 5", -2},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		testFloatObject(t, evaluated, tt.expected)
+	}
+}
+
 func TestEvalBooleanExpression(t *testing.T) {
 	tests := []struct {
 		input    string
@@ -59,6 +250,22 @@ func TestEvalBooleanExpression(t *testing.T) {
 		{"(1 < 2) == false", false},
 		{"(1 > 2) == true", false},
 		{"(1 > 2) == false", true},
+		{"1.5 < 2", true},
+		{"2 > 1.5", true},
+		{"1 == 1.0", true},
+		{"0.1 + 0.2 != 0.3", true},
+		{"2.5 == 2.5", true},
+		{"1 <= 1", true},
+		{"1 <= 0", false},
+		{"1 >= 1", true},
+		{"0 >= 1", false},
+		{"1.5 <= 1.5", true},
+		{"2 >= 2.5", false},
+		{"(1 << 70) >= (1 << 70)", true},
+		{`"abc" == "abc"`, true},
+		{`"abc" != "abd"`, true},
+		{`"abc" < "abd"`, true},
+		{`"b" >= "a"`, true},
 	}
 
 	for _, tt := range tests {
@@ -98,6 +305,10 @@ func TestIfElseExpressions(t *testing.T) {
 		{"if (1 > 2) { 10 }", nil},
 		{"if (1 > 2) { 10 } else { 20 }", 20},
 		{"if (1 < 2) { 10 } else { 20 }", 10},
+		{"if (1 > 2) { 10 } else if (2 > 1) { 20 } else { 30 }", 20},
+		{"if (1 > 2) { 10 } else if (2 > 3) { 20 } else { 30 }", 30},
+		{"if (1 > 2) { 10 } else if (2 > 3) { 20 }", nil},
+		{"if (false) { 1 } else if (false) { 2 } else if (true) { 3 } else { 4 }", 3},
 	}
 
 	for _, tt := range tests {
@@ -111,6 +322,66 @@ func TestIfElseExpressions(t *testing.T) {
 	}
 }
 
+func TestMatchExpressions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected int
+	}{
+		{"match (1) { 1 => 10, _ => 20 }", 10},
+		{"match (2) { 1 => 10, _ => 20 }", 20},
+		{"match (-3) { -3 => 1, _ => 2 }", 1},
+		{"match (2.0) { 2 => 1, _ => 2 }", 1},
+		{`match ("b") { "a" => 1, "b" => 2 }`, 2},
+		{"match (1 < 2) { false => 1, true => 2 }", 2},
+		{"match (5) { n => n * 2 }", 10},
+		{"match (5) { 1 => 0, n => { let m = n + 1; m * 2 } }", 12},
+		{"match ([]) { [] => 0, _ => 1 }", 0},
+		{"match ([1, 2]) { [a] => a, [a, b] => a + b }", 3},
+		{"match ([1, 2]) { [1, 3] => 0, [1, x] => x }", 2},
+		{"match ([1, 2, 3, 4]) { [a, ...rest] => len(rest) }", 3},
+		{"match ([1]) { [a, b, ...rest] => 0, [a, ...rest] => len(rest) }", 0},
+		{"match ([[1, 2], 3]) { [[a, b], c] => a + b + c }", 6},
+		{`match ({"x": 1, "y": 2}) { {"x": 1, y} => y, _ => 0 }`, 2},
+		{`match ({"x": 1}) { {"x": 2} => 1, {"z": z} => 2, {x} => x + 10 }`, 11},
+		{`match ({"pt": [1, 2]}) { {pt: [a, b]} => a * b }`, 2},
+		{"match (5) { [a] => a, {a} => a, _ => 7 }", 7},
+		{"let n = 1; match (2) { n => n }; n", 1},
+		{"let f = fn(x) { match (x) { 0 => { return 100; } _ => 1 }; 5 }; f(0)", 100},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		testIntegerObject(t, evaluated, int64(tt.expected))
+	}
+}
+
+func TestMatchExpressionErrors(t *testing.T) {
+	tests := []struct {
+		input           string
+		expectedMessage string
+	}{
+		{"match (3) { 1 => 1, 2 => 2 }", "no match arm for 3"},
+		{`match ([1, "a"]) { [x] => x }`, "no match arm for [1, a]"},
+		{"match (1) { x => x + true }", "type mismatch: INTEGER + BOOLEAN"},
+		{"match (undefined) { _ => 1 }", "identifier not found: undefined"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+
+		errObj, ok := evaluated.(*object.Error)
+		if !ok {
+			t.Errorf("no error object returned. got=%T(%+v)", evaluated, evaluated)
+			continue
+		}
+
+		if errObj.Message != tt.expectedMessage {
+			t.Errorf("wrong error message. expected=%q, got=%q",
+				tt.expectedMessage, errObj.Message)
+		}
+	}
+}
+
 func TestReturnStatements(t *testing.T) {
 	tests := []struct {
 		input    string
@@ -221,6 +492,26 @@ if (10 > 1) {
 			`999[1]`,
 			"index operator not supported: INTEGER",
 		},
+		{
+			"10 / (5 - 5)",
+			"division by zero",
+		},
+		{
+			"10 % 0",
+			"division by zero",
+		},
+		{
+			"1 << -1",
+			"negative shift count: -1",
+		},
+		{
+			"1.5 & 1",
+			"unknown operator: FLOAT & INTEGER",
+		},
+		{
+			"~true",
+			"unknown operator: ~BOOLEAN",
+		},
 	}
 
 	for _, tt := range tests {
@@ -240,6 +531,174 @@ if (10 > 1) {
 	}
 }
 
+func TestErrorPositions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"5 + true;", "ERROR: 1:1: type mismatch: INTEGER + BOOLEAN"},
+		{"let x = 1;\nlet y = x + foo;", "ERROR: 2:13: identifier not found: foo"},
+		{"let f = fn() {\n  -true\n};\nf()", "ERROR: 2:3: unknown operator: -BOOLEAN\n    at f (4:1)"},
+		{"fn outer(x) { inner(x) }\nfn inner(y) { y + true }\nouter(1)",
+			"ERROR: 2:15: type mismatch: INTEGER + BOOLEAN\n    at inner (1:15)\n    at outer (3:1)"},
+		{"fn(x) { x / 0 }(1)", "ERROR: 1:9: division by zero\n    at <anonymous> (1:1)"},
+		{`len(1)`, "ERROR: 1:1: argument to `len` not supported, got INTEGER"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+
+		errObj, ok := evaluated.(*object.Error)
+		if !ok {
+			t.Errorf("no error object returned. got=%T(%+v)",
+				evaluated, evaluated)
+			continue
+		}
+
+		if errObj.Inspect() != tt.expected {
+			t.Errorf("wrong error. expected=%q, got=%q",
+				tt.expected, errObj.Inspect())
+		}
+	}
+}
+
+func TestTryCatch(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"try { 1 } catch (e) { 2 }", 1},
+		{"try { 1 + true } catch (e) { 2 }", 2},
+		{"try { throw 5; 1 } catch (e) { e * 2 }", 10},
+		{`try { 1 + true } catch (e) { e["kind"] }`, "TypeError"},
+		{`try { 1 + true } catch (e) { e["message"] }`, "type mismatch: INTEGER + BOOLEAN"},
+		{`try { x } catch (e) { e["kind"] }`, "NameError"},
+		{`try { 1 / 0 } catch (e) { e["kind"] }`, "ArithmeticError"},
+		{`try { let a = [1]; a[3] = 1; } catch (e) { e["kind"] }`, "IndexError"},
+		{`try { len(1, 2) } catch (e) { e["kind"] }`, "ArgumentError"},
+		{`try { len(1) } catch (e) { e["message"] }`, "argument to `len` not supported, got INTEGER"},
+		{`try { match (1) { 2 => 2 } } catch (e) { e["kind"] }`, "MatchError"},
+		{`try { throw {"kind": "custom"} } catch (e) { e["kind"] }`, "custom"},
+		{"let f = fn() { throw 7; }; try { f() } catch (e) { e }", 7},
+		{"try { try { throw 1; } catch (e) { throw e + 1; } } catch (e) { e }", 2},
+		{"try { throw 1; } catch { 3 }", 3},
+		{"let n = 0; try { n = 1; } finally { n = n + 10; }; n", 11},
+		{"let n = 0; try { throw 1; } catch (e) { n = 5; } finally { n *= 2; }; n", 10},
+		{"try { 1 } finally { 2 }", 1},
+		{"let f = fn() { try { return 1; } finally { return 2; } }; f()", 2},
+		{"let f = fn() { try { return 1; } catch (e) { 5 } }; f() + 1", 2},
+		{"let i = 0; while (true) { try { i += 1; if (i > 2) { break; } } catch (e) { 0 } }; i", 3},
+		{"let e = 1; try { throw 2; } catch (e) { e }; e", 1},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case string:
+			str, ok := evaluated.(*object.String)
+			if !ok {
+				t.Errorf("object is not String. got=%T (%+v)", evaluated, evaluated)
+				continue
+			}
+			if str.Value != expected {
+				t.Errorf("String has wrong value. expected=%q, got=%q", expected, str.Value)
+			}
+		}
+	}
+}
+
+func TestUncaughtErrors(t *testing.T) {
+	tests := []struct {
+		input           string
+		expectedKind    string
+		expectedMessage string
+	}{
+		{"throw 1;", object.THROWN_ERROR, "uncaught exception: 1"},
+		{`throw "boom"; 2`, object.THROWN_ERROR, "uncaught exception: boom"},
+		{"try { throw 1; } finally { 2 }", object.THROWN_ERROR, "uncaught exception: 1"},
+		{"try { 1 } catch (e) { 2 } finally { 1 + true }", object.TYPE_ERROR, "type mismatch: INTEGER + BOOLEAN"},
+		{"try { throw 1; } catch (e) { -true }", object.TYPE_ERROR, "unknown operator: -BOOLEAN"},
+		{"throw x;", object.NAME_ERROR, "identifier not found: x"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+
+		errObj, ok := evaluated.(*object.Error)
+		if !ok {
+			t.Errorf("no error object returned. got=%T(%+v)", evaluated, evaluated)
+			continue
+		}
+
+		if errObj.Kind != tt.expectedKind {
+			t.Errorf("wrong error kind. expected=%q, got=%q", tt.expectedKind, errObj.Kind)
+		}
+
+		if errObj.Message != tt.expectedMessage {
+			t.Errorf("wrong error message. expected=%q, got=%q",
+				tt.expectedMessage, errObj.Message)
+		}
+	}
+}
+
+func TestLoops(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"while (false) { 1 }", nil},
+		{"while (true) { break; }", nil},
+		{"let f = fn() { while (true) { return 5; } }; f()", 5},
+		{"for (x in [1, 2, 3]) { x }", nil},
+		{"for (x in []) { return 1; }", nil},
+		{"let f = fn(arr) { for (x in arr) { if (x > 2) { return x; } } }; f([1, 2, 3, 4])", 3},
+		{"let f = fn() { for (x in [1, 2, 3]) { if (x < 3) { continue; } return x; } }; f()", 3},
+		{"let f = fn() { for (x in [1, 2, 3]) { if (x == 2) { break; } } return 10; }; f()", 10},
+		{`let f = fn() { for (k in {"a": 1}) { return k; } }; f()`, "a"},
+		{`let f = fn() { for (c in "日本") { if (c != "日") { return c; } } }; f()`, "本"},
+		{`let f = fn() {
+  for (x in [1, 2]) {
+    for (y in [10, 20]) {
+      if (y == 20) { break; }
+      if (x == 2) { return x + y; }
+    }
+  }
+}; f()`, 12},
+		{"let x = 1; for (x in [5]) { x }; x", 1},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case string:
+			str, ok := evaluated.(*object.String)
+			if !ok {
+				t.Errorf("object is not String. got=%T (%+v)", evaluated, evaluated)
+				continue
+			}
+			if str.Value != expected {
+				t.Errorf("String has wrong value. want=%q, got=%q", expected, str.Value)
+			}
+		case nil:
+			testNullObject(t, evaluated)
+		}
+	}
+
+	evaluated := testEval("for (x in 5) { x }")
+	errObj, ok := evaluated.(*object.Error)
+	if !ok {
+		t.Fatalf("no error object returned. got=%T(%+v)", evaluated, evaluated)
+	}
+	if errObj.Message != "cannot iterate over INTEGER" {
+		t.Errorf("wrong error message. got=%q", errObj.Message)
+	}
+}
+
 func TestLetStatements(t *testing.T) {
 	tests := []struct {
 		input    string
@@ -256,6 +715,163 @@ func TestLetStatements(t *testing.T) {
 	}
 }
 
+func TestAssignExpressions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected int64
+	}{
+		{"let a = 5; a = 10; a;", 10},
+		{"let a = 5; a = 10;", 10},
+		{"let a = 5; a += 3; a;", 8},
+		{"let a = 5; a -= 3; a;", 2},
+		{"let a = 5; a *= 3; a;", 15},
+		{"let a = 15; a /= 3; a;", 5},
+		{"let a = 17; a %= 5; a;", 2},
+		{"let a = 1; let b = 2; a = b = 7; a + b;", 14},
+		{"let a = 1; let f = fn() { a = 2; }; f(); a;", 2},
+		{"let a = 1; let f = fn(a) { a = 2; }; f(5); a;", 1},
+		{"let i = 0; while (i < 5) { i += 1; } i;", 5},
+		{`
+let counter = fn() {
+  let count = 0;
+  fn() { count += 1; };
+};
+let next = counter();
+next();
+next();
+next();`, 3},
+	}
+
+	for _, tt := range tests {
+		testIntegerObject(t, testEval(tt.input), tt.expected)
+	}
+}
+
+func TestIndexAssignment(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"let a = [1, 2, 3]; a[0] = 10; a[0];", 10},
+		{"let a = [1, 2, 3]; a[2] = 10;", 10},
+		{"let a = [1, 2, 3]; a[1] += 5; a[1];", 7},
+		{"let a = [[1], [2]]; a[1][0] = 9; a[1][0];", 9},
+		{"let a = [1, 2, 3]; let b = a; b[0] = 7; a[0];", 7},
+		{"let a = [0, 0, 0]; let i = 0; while (i < 3) { a[i] = i * i; i += 1; } a[2];", 4},
+		{`let h = {"a": 1}; h["a"] = 5; h["a"];`, 5},
+		{`let h = {}; h["b"] = 2; h["b"];`, 2},
+		{`let h = {}; h[true] = 3; h[true];`, 3},
+		{`let h = {"n": 1}; h["n"] *= 4; h["n"];`, 4},
+		{`let h = {"xs": [1, 2]}; h["xs"][1] = 8; h["xs"][1];`, 8},
+		{`let set = fn(h) { h["k"] = 1; }; let h = {}; set(h); h["k"];`, 1},
+		{`let a = [1]; a[0] = "one"; a[0];`, "one"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case string:
+			str, ok := evaluated.(*object.String)
+			if !ok {
+				t.Errorf("object is not String. got=%T (%+v)", evaluated, evaluated)
+				continue
+			}
+			if str.Value != expected {
+				t.Errorf("String has wrong value. got=%q", str.Value)
+			}
+		}
+	}
+}
+
+func TestAssignExpressionErrors(t *testing.T) {
+	tests := []struct {
+		input           string
+		expectedMessage string
+	}{
+		{"x = 5;", "assignment to undeclared identifier: x"},
+		{"x += 5;", "assignment to undeclared identifier: x"},
+		{"let f = fn() { y = 1; }; f();", "assignment to undeclared identifier: y"},
+		{`let s = "a"; s -= 1;`, "type mismatch: STRING - INTEGER"},
+		{"let a = 1; a /= 0;", "division by zero"},
+		{"let a = [1, 2]; a[2] = 3;", "index out of range: 2 (length 2)"},
+		{"let a = [1, 2]; a[-1] = 3;", "index out of range: -1 (length 2)"},
+		{`let a = [1, 2]; a["x"] = 3;`, "array index must be INTEGER, got STRING"},
+		{`let h = {}; h[fn(x) { x }] = 1;`, "unusable as hash key: FUNCTION"},
+		{`let h = {}; h["a"] += 1;`, "key not found: a"},
+		{`let s = "abc"; s[0] = "x";`, "index assignment not supported: STRING"},
+		{`undefined[0] = 1;`, "identifier not found: undefined"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+
+		errObj, ok := evaluated.(*object.Error)
+		if !ok {
+			t.Errorf("no error object returned. got=%T(%+v)", evaluated, evaluated)
+			continue
+		}
+
+		if errObj.Message != tt.expectedMessage {
+			t.Errorf("wrong error message. expected=%q, got=%q",
+				tt.expectedMessage, errObj.Message)
+		}
+	}
+}
+
+func TestDestructuringLetStatements(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected int64
+	}{
+		{"let [a, b] = [1, 2]; a * 10 + b;", 12},
+		{"let [a, _, c] = [1, 2, 3]; a + c;", 4},
+		{"let [first, ...rest] = [1, 2, 3]; first + len(rest);", 3},
+		{"let [...all] = []; len(all);", 0},
+		{"let [[a, b], c] = [[1, 2], 3]; a + b + c;", 6},
+		{`let {name, age} = {"name": "x", "age": 30}; age;`, 30},
+		{`let {"pos": [x, y]} = {"pos": [3, 4], "id": 1}; x * y;`, 12},
+		{`let {pos: p} = {"pos": 5}; p;`, 5},
+		{`let {1: one, true: yes} = {1: 10, true: 20}; one + yes;`, 30},
+		{"let f = fn(pair) { let [a, b] = pair; a - b }; f([5, 3]);", 2},
+	}
+
+	for _, tt := range tests {
+		testIntegerObject(t, testEval(tt.input), tt.expected)
+	}
+}
+
+func TestDestructuringErrors(t *testing.T) {
+	tests := []struct {
+		input           string
+		expectedMessage string
+	}{
+		{"let [a, b] = [1];", "cannot destructure [1]: expected 2 elements, got 1"},
+		{"let [a, b, ...c] = [1];", "cannot destructure [1]: expected at least 2 elements, got 1"},
+		{"let [a] = 1;", "cannot destructure 1: expected ARRAY, got INTEGER"},
+		{"let {a} = [1];", "cannot destructure [1]: expected HASH, got ARRAY"},
+		{`let {name, age} = {"name": "x"};`, "cannot destructure {name: x}: missing key age"},
+		{"let [[a, b]] = [[1]];", "cannot destructure [[1]]: expected 2 elements, got 1"},
+		{"let [1, a] = [2, 3];", "cannot destructure [2, 3]: expected 1, got 2"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+
+		errObj, ok := evaluated.(*object.Error)
+		if !ok {
+			t.Errorf("no error object returned. got=%T(%+v)", evaluated, evaluated)
+			continue
+		}
+
+		if errObj.Message != tt.expectedMessage {
+			t.Errorf("wrong error message. expected=%q, got=%q",
+				tt.expectedMessage, errObj.Message)
+		}
+	}
+}
+
 func TestFunctionObject(t *testing.T) {
 	input := "fn(x) { x + 2; };"
 
@@ -299,6 +915,112 @@ func TestFunctionApplication(t *testing.T) {
 	}
 }
 
+func TestFunctionParameters(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected int64
+	}{
+		{"let f = fn(x, y = 10) { x + y }; f(1);", 11},
+		{"let f = fn(x, y = 10) { x + y }; f(1, 2);", 3},
+		{"let f = fn(x, y = x * 2) { x + y }; f(3);", 9},
+		{"let n = 5; let f = fn(x = n) { x }; f();", 5},
+		{"let f = fn(...xs) { len(xs) }; f();", 0},
+		{"let f = fn(...xs) { len(xs) }; f(1, 2, 3);", 3},
+		{"let f = fn(a, ...xs) { a + xs[0] + xs[1] }; f(1, 2, 3);", 6},
+		{"let f = fn(a, b) { a - b }; f(b: 1, a: 10);", 9},
+		{"let f = fn(a, b = 2, c = 3) { a * 100 + b * 10 + c }; f(1, c: 9);", 129},
+		{"let f = fn(a, b = 2, ...r) { len(r) }; f(1, 2, 3, 4);", 2},
+		{"let calls = 0; let f = fn(x = calls += 1) { x }; f(7); f(); f();", 2},
+	}
+
+	for _, tt := range tests {
+		testIntegerObject(t, testEval(tt.input), tt.expected)
+	}
+}
+
+func TestFunctionArgumentErrors(t *testing.T) {
+	tests := []struct {
+		input           string
+		expectedMessage string
+	}{
+		{"let f = fn(x, y) { x }; f(1);", "wrong number of arguments. got=1, want=2"},
+		{"let f = fn(x) { x }; f(1, 2);", "wrong number of arguments. got=2, want=1"},
+		{"let f = fn(x, y = 1) { x }; f();", "wrong number of arguments. got=0, want=1..2"},
+		{"let f = fn(x, ...r) { x }; f();", "wrong number of arguments. got=0, want=1+"},
+		{"let f = fn(x, y) { x }; f(y: 1);", "missing argument: x"},
+		{"let f = fn(x) { x }; f(1, x: 2);", "multiple values for argument: x"},
+		{"let f = fn(x) { x }; f(z: 1, y: 2);", "unexpected keyword argument: y"},
+		{"let f = fn(...r) { r }; f(r: 1);", "unexpected keyword argument: r"},
+		{"let f = fn(x = y) { x }; f();", "identifier not found: y"},
+		{`len(x: "a")`, "builtin functions do not accept keyword arguments"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+
+		errObj, ok := evaluated.(*object.Error)
+		if !ok {
+			t.Errorf("no error object returned. got=%T(%+v)", evaluated, evaluated)
+			continue
+		}
+
+		if errObj.Message != tt.expectedMessage {
+			t.Errorf("wrong error message. expected=%q, got=%q",
+				tt.expectedMessage, errObj.Message)
+		}
+	}
+}
+
+func TestFunctionDeclarations(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected int64
+	}{
+		{"fn add(a, b) { a + b } add(1, 2);", 3},
+		{"let x = double(4); fn double(n) { n * 2 }; x;", 8},
+		{`
+fn isEven(n) { if (n == 0) { true } else { isOdd(n - 1) } }
+fn isOdd(n) { if (n == 0) { false } else { isEven(n - 1) } }
+if (isEven(10)) { 1 } else { 0 }`, 1},
+		{`
+let f = fn() {
+  let r = g();
+  fn g() { 42 }
+  r
+};
+f();`, 42},
+		{"fn fact(n) { if (n < 2) { return 1; } n * fact(n - 1) } fact(5);", 120},
+		{"fn f() { 1 } let g = f; fn f() { 2 } g();", 2},
+	}
+
+	for _, tt := range tests {
+		testIntegerObject(t, testEval(tt.input), tt.expected)
+	}
+}
+
+func TestFunctionNames(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"fn add(a, b) { a + b } add", "fn add(a, b) {\n(a + b)\n}"},
+		{"let sub = fn(a, b) { a - b }; sub", "fn sub(a, b) {\n(a - b)\n}"},
+		{"fn(a) { a }", "fn(a) {\na\n}"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		fn, ok := evaluated.(*object.Function)
+		if !ok {
+			t.Fatalf("object is not Function. got=%T (%+v)", evaluated, evaluated)
+		}
+
+		if fn.Inspect() != tt.expected {
+			t.Errorf("wrong Inspect. expected=%q, got=%q", tt.expected, fn.Inspect())
+		}
+	}
+}
+
 func TestEnclosingEnvironments(t *testing.T) {
 	input := `
 let first = 10;
@@ -342,6 +1064,58 @@ func TestStringLiteral(t *testing.T) {
 	}
 }
 
+func TestUnicodeIdentifiers(t *testing.T) {
+	input := `let 名前 = "モンキー";
+let 挨拶 = fn(相手) { "こんにちは、" + 相手 + "さん" };
+挨拶(名前)`
+
+	evaluated := testEval(input)
+	str, ok := evaluated.(*object.String)
+	if !ok {
+		t.Fatalf("object is not String. got=%T (%+v)", evaluated, evaluated)
+	}
+
+	if str.Value != "こんにちは、モンキーさん" {
+		t.Errorf("String has wrong value. got=%q", str.Value)
+	}
+}
+
+func TestTemplateLiterals(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`let name = "Monkey"; "Hello ${name}!"`, "Hello Monkey!"},
+		{`let items = [1, 2, 3]; "you have ${len(items)} items"`, "you have 3 items"},
+		{`"${1 + 1} ${2.5} ${true} ${[1, "a"]}"`, "2 2.5 true [1, a]"},
+		{`"outer ${"inner ${1 * 3}"}"`, "outer inner 3"},
+		{`"""sum: ${1 + 2}"""`, "sum: 3"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		str, ok := evaluated.(*object.String)
+		if !ok {
+			t.Errorf("object is not String. got=%T (%+v)", evaluated, evaluated)
+			continue
+		}
+
+		if str.Value != tt.expected {
+			t.Errorf("String has wrong value. want=%q, got=%q",
+				tt.expected, str.Value)
+		}
+	}
+
+	evaluated := testEval(`"value: ${missing}"`)
+	errObj, ok := evaluated.(*object.Error)
+	if !ok {
+		t.Fatalf("no error object returned. got=%T(%+v)", evaluated, evaluated)
+	}
+	if errObj.Message != "identifier not found: missing" {
+		t.Errorf("wrong error message. got=%q", errObj.Message)
+	}
+}
+
 func TestStringConcatenation(t *testing.T) {
 	input := `"Hello" + " " + "World!"`
 
@@ -364,10 +1138,15 @@ func TestBuiltinFunctions(t *testing.T) {
 		{`len("")`, 0},
 		{`len("four")`, 4},
 		{`len("hello world")`, 11},
+		{`len("こんにちは")`, 5},
+		{`len("🐵🙈")`, 2},
 		{`len(1)`, "argument to `len` not supported, got INTEGER"},
 		{`len("one", "two")`, "wrong number of arguments. got=2, want=1"},
 		{`len([1, 2, 3])`, 3},
 		{`len([])`, 0},
+		{`bytelen("hello")`, 5},
+		{`bytelen("こんにちは")`, 15},
+		{`bytelen([])`, "argument to `bytelen` must be STRING, got ARRAY"},
 		{`puts("hello", "world!")`, nil},
 		{`first([1, 2, 3])`, 1},
 		{`first([])`, nil},
@@ -377,6 +1156,13 @@ func TestBuiltinFunctions(t *testing.T) {
 		{`last(1)`, "argument to `last` must be ARRAY, got INTEGER"},
 		{`rest([1, 2, 3])`, []int{2, 3}},
 		{`rest([])`, nil},
+		{`int(3.99)`, 3},
+		{`int(-3.99)`, -3},
+		{`int(7)`, 7},
+		{`int("7")`, "argument to `int` not supported, got STRING"},
+		{`float(3)`, 3.0},
+		{`float(2.5)`, 2.5},
+		{`float(true)`, "argument to `float` not supported, got BOOLEAN"},
 		{`push([], 1)`, []int{1}},
 		{`push(1, 1)`, "argument to `push` must be ARRAY, got INTEGER"},
 	}
@@ -387,6 +1173,8 @@ func TestBuiltinFunctions(t *testing.T) {
 		switch expected := tt.expected.(type) {
 		case int:
 			testIntegerObject(t, evaluated, int64(expected))
+		case float64:
+			testFloatObject(t, evaluated, expected)
 		case nil:
 			testNullObject(t, evaluated)
 		case string:
@@ -537,6 +1325,32 @@ func TestHashLiterals(t *testing.T) {
 	}
 }
 
+func TestHashInsertionOrder(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`{"b": 1, "a": 2, 3: 3, true: 4}`, "{b: 1, a: 2, 3: 3, true: 4}"},
+		{`{"a": 1, "b": 2, "a": 3}`, "{a: 3, b: 2}"},
+		{`let h = {"b": 1, "a": 2}; h["b"] = 3; h["c"] = 4; h`, "{b: 3, a: 2, c: 4}"},
+		{`let keys = []; for (k in {"z": 1, "y": 2, "x": 3}) { keys = push(keys, k) }; keys`, "[z, y, x]"},
+		{
+			`let log = []; let f = fn(x) { log = push(log, x); x };
+			{f("k1"): f(1), f("k2"): f(2)}; log`,
+			"[k1, 1, k2, 2]",
+		},
+		{`try { 1 + true } catch (e) { e }`, "{kind: TypeError, message: type mismatch: INTEGER + BOOLEAN}"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		if evaluated.Inspect() != tt.expected {
+			t.Errorf("wrong Inspect for %q. expected=%q, got=%q",
+				tt.input, tt.expected, evaluated.Inspect())
+		}
+	}
+}
+
 func TestHashIndexExpressions(t *testing.T) {
 	tests := []struct {
 		input    string
@@ -570,6 +1384,14 @@ func TestHashIndexExpressions(t *testing.T) {
 			`{false: 5}[false]`,
 			5,
 		},
+		{
+			`{2.5: 5}[2.5]`,
+			5,
+		},
+		{
+			`{1: 5}[1.0]`,
+			5,
+		},
 	}
 
 	for _, tt := range tests {
@@ -582,11 +1404,45 @@ func TestHashIndexExpressions(t *testing.T) {
 		}
 	}
 }
+
+type recordingObserver struct {
+	calls []string
+}
+
+func (r *recordingObserver) EnterCall(call *ast.CallExpression, fn object.Object) {
+	r.calls = append(r.calls, "enter "+call.Function.String())
+}
+
+func (r *recordingObserver) ExitCall(call *ast.CallExpression, fn object.Object) {
+	r.calls = append(r.calls, "exit "+call.Function.String())
+}
+
+func TestCallObserver(t *testing.T) {
+	observer := &recordingObserver{}
+
+	input := `let f = fn(x) { len(x) }; let g = fn(a) { a }; f(g([1]));`
+	testEvalWithOptions(input, object.Options{Observer: observer})
+	testEval(input)
+
+	expected := []string{
+		"enter g", "exit g",
+		"enter f", "enter len", "exit len", "exit f",
+	}
+	if strings.Join(observer.calls, ", ") != strings.Join(expected, ", ") {
+		t.Errorf("wrong calls. want=%v, got=%v", expected, observer.calls)
+	}
+}
+
 func testEval(input string) object.Object {
+	return testEvalWithOptions(input, object.Options{})
+}
+
+func testEvalWithOptions(input string, options object.Options) object.Object {
 	l := lexer.New(input)
 	p := parser.New(l)
 	program := p.ParseProgram()
 	env := object.NewEnvironment()
+	*env.Options() = options
 
 	return Eval(program, env)
 }
@@ -606,6 +1462,36 @@ func testIntegerObject(t *testing.T, obj object.Object, expected int64) bool {
 	return true
 }
 
+func testBigIntObject(t *testing.T, obj object.Object, expected string) bool {
+	result, ok := obj.(*object.BigInt)
+	if !ok {
+		t.Errorf("object is not BigInt. got=%T (%+v)", obj, obj)
+		return false
+	}
+	if result.Value.String() != expected {
+		t.Errorf("object has wrong value. got=%s, want=%s",
+			result.Value.String(), expected)
+		return false
+	}
+
+	return true
+}
+
+func testFloatObject(t *testing.T, obj object.Object, expected float64) bool {
+	result, ok := obj.(*object.Float)
+	if !ok {
+		t.Errorf("object is not Float. got=%T (%+v)", obj, obj)
+		return false
+	}
+	if result.Value != expected {
+		t.Errorf("object has wrong value. got=%g, want=%g",
+			result.Value, expected)
+		return false
+	}
+
+	return true
+}
+
 func testBooleanObject(t *testing.T, obj object.Object, expected bool) bool {
 	result, ok := obj.(*object.Boolean)
 	if !ok {
@@ -0,0 +1,156 @@
+package evaluator
+
+import (
+	"monkey/ast"
+	"monkey/object"
+)
+
+// DefineMacros removes the top-level `let name = macro(...) { ... };`
+// statements from program and binds the macros they define in env.
+func DefineMacros(program *ast.Program, env *object.Environment) {
+	definitions := []int{}
+
+	for i, statement := range program.Statements {
+		if isMacroDefinition(statement) {
+			addMacro(statement, env)
+			definitions = append(definitions, i)
+		}
+	}
+
+	for i := len(definitions) - 1; i >= 0; i = i - 1 {
+		definitionIndex := definitions[i]
+		program.Statements = append(
+			program.Statements[:definitionIndex],
+			program.Statements[definitionIndex+1:]...,
+		)
+	}
+}
+
+func isMacroDefinition(node ast.Statement) bool {
+	letStatement, ok := node.(*ast.LetStatement)
+	if !ok || letStatement.Name == nil {
+		return false
+	}
+
+	_, ok = letStatement.Value.(*ast.MacroLiteral)
+	return ok
+}
+
+func addMacro(stmt ast.Statement, env *object.Environment) {
+	letStatement, _ := stmt.(*ast.LetStatement)
+	macroLiteral, _ := letStatement.Value.(*ast.MacroLiteral)
+
+	macro := &object.Macro{
+		Parameters: macroLiteral.Parameters,
+		Env:        env,
+		Body:       macroLiteral.Body,
+	}
+
+	env.Set(letStatement.Name.Value, macro)
+}
+
+// ExpandMacros replaces every call to a macro defined in env with the AST
+// node its body returns. The arguments are passed to the macro unevaluated,
+// as quotes. If a macro is called with the wrong number of arguments or does
+// not return a quote, ExpandMacros returns an error at the position of the
+// first such call.
+func ExpandMacros(program ast.Node, env *object.Environment) (ast.Node, *object.Error) {
+	var expandErr *object.Error
+
+	expanded := ast.Modify(program, func(node ast.Node) ast.Node {
+		if expandErr != nil {
+			return node
+		}
+
+		callExpression, ok := node.(*ast.CallExpression)
+		if !ok {
+			return node
+		}
+
+		macro, ok := isMacroCall(callExpression, env)
+		if !ok {
+			return node
+		}
+
+		args := quoteArgs(callExpression)
+		if len(args) != len(macro.Parameters) {
+			expandErr = newError(object.ARGUMENT_ERROR,
+				"wrong number of arguments to macro %s. got=%d, want=%d",
+				callExpression.Function, len(args), len(macro.Parameters))
+			expandErr.Pos = callExpression.Pos()
+			return node
+		}
+		evalEnv := extendMacroEnv(macro, args)
+
+		evaluated := Eval(macro.Body, evalEnv)
+
+		if err, ok := evaluated.(*object.Error); ok {
+			expandErr = err
+			return node
+		}
+
+		quote, ok := evaluated.(*object.Quote)
+		if !ok {
+			got := "nothing"
+			if evaluated != nil {
+				got = string(evaluated.Type())
+			}
+			expandErr = newError(object.TYPE_ERROR,
+				"macro %s must return a quote, got %s", callExpression.Function, got)
+			expandErr.Pos = callExpression.Pos()
+			return node
+		}
+
+		return quote.Node
+	})
+
+	if expandErr != nil {
+		return nil, expandErr
+	}
+	return expanded, nil
+}
+
+func isMacroCall(
+	exp *ast.CallExpression,
+	env *object.Environment,
+) (*object.Macro, bool) {
+	identifier, ok := exp.Function.(*ast.Identifier)
+	if !ok {
+		return nil, false
+	}
+
+	obj, ok := env.Get(identifier.Value)
+	if !ok {
+		return nil, false
+	}
+
+	macro, ok := obj.(*object.Macro)
+	if !ok {
+		return nil, false
+	}
+
+	return macro, true
+}
+
+func quoteArgs(exp *ast.CallExpression) []*object.Quote {
+	args := []*object.Quote{}
+
+	for _, a := range exp.Arguments {
+		args = append(args, &object.Quote{Node: a})
+	}
+
+	return args
+}
+
+func extendMacroEnv(
+	macro *object.Macro,
+	args []*object.Quote,
+) *object.Environment {
+	extended := object.NewEnclosedEnvironment(macro.Env)
+
+	for paramIdx, param := range macro.Parameters {
+		extended.Set(param.Value, args[paramIdx])
+	}
+
+	return extended
+}
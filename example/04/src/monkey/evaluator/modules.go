@@ -0,0 +1,182 @@
+package evaluator
+
+import (
+	"io/ioutil"
+	"monkey/ast"
+	"monkey/lexer"
+	"monkey/object"
+	"monkey/parser"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// ModuleExtension is appended to import paths that have no extension.
+const ModuleExtension = ".monkey"
+
+// evalImportExpression evaluates the imported file once per program: the
+// module is cached in the Imports of env, which are shared by every
+// environment of the program and of the modules it imports.
+func evalImportExpression(ie *ast.ImportExpression, env *object.Environment) object.Object {
+	path, err := resolveImport(ie)
+	if err != nil {
+		return newError(object.IMPORT_ERROR, "cannot import %q: %s", ie.Path.Value, err)
+	}
+
+	imports := env.Imports()
+	if module, ok := imports.Modules[path]; ok {
+		return module
+	}
+
+	for i, p := range imports.Importing {
+		if p == path {
+			cycle := append(append([]string{}, imports.Importing[i:]...), path)
+			return newError(object.IMPORT_ERROR, "circular import: %s",
+				strings.Join(cycle, " -> "))
+		}
+	}
+
+	imports.Importing = append(imports.Importing, path)
+	defer func() { imports.Importing = imports.Importing[:len(imports.Importing)-1] }()
+
+	result := evalModule(path, env)
+	if module, ok := result.(*object.Module); ok {
+		imports.Modules[path] = module
+	}
+	return result
+}
+
+// resolveImport turns an import path into an absolute file name. Relative
+// paths are resolved against the directory of the importing file.
+func resolveImport(ie *ast.ImportExpression) (string, error) {
+	path := ie.Path.Value
+	if filepath.Ext(path) == "" {
+		path += ModuleExtension
+	}
+
+	if !filepath.IsAbs(path) && ie.Token.Pos.Filename != "" {
+		path = filepath.Join(filepath.Dir(ie.Token.Pos.Filename), path)
+	}
+
+	return filepath.Abs(path)
+}
+
+func evalModule(path string, importer *object.Environment) object.Object {
+	input, err := ioutil.ReadFile(path)
+	if err != nil {
+		return newError(object.IMPORT_ERROR, "cannot import %q: %s", path, err)
+	}
+
+	p := parser.New(lexer.NewFile(path, string(input)))
+	program := p.ParseProgram()
+	if errors := p.Errors(); len(errors) != 0 {
+		return newError(object.IMPORT_ERROR, "cannot import %q: %s", path, errors[0])
+	}
+
+	macroEnv := object.NewModuleEnvironment(importer)
+	DefineMacros(program, macroEnv)
+	expanded, expandErr := ExpandMacros(program, macroEnv)
+	if expandErr != nil {
+		return expandErr
+	}
+
+	env := object.NewModuleEnvironment(importer)
+	if result := Eval(expanded, env); isError(result) {
+		return result
+	}
+
+	module := &object.Module{Path: path, Exports: make(map[string]object.Object)}
+	for _, name := range exportedNames(program) {
+		if val, ok := env.Get(name); ok {
+			module.Exports[name] = val
+		}
+	}
+
+	return module
+}
+
+func exportedNames(program *ast.Program) []string {
+	names := []string{}
+
+	for _, statement := range program.Statements {
+		export, ok := statement.(*ast.ExportStatement)
+		if !ok {
+			continue
+		}
+
+		switch stmt := export.Statement.(type) {
+		case *ast.LetStatement:
+			if stmt.Name != nil {
+				names = append(names, stmt.Name.Value)
+			} else {
+				names = append(names, patternNames(stmt.Pattern)...)
+			}
+		case *ast.FunctionStatement:
+			names = append(names, stmt.Name.Value)
+		}
+	}
+
+	return names
+}
+
+// patternNames returns the names a destructuring pattern binds.
+func patternNames(pattern ast.Pattern) []string {
+	switch pattern := pattern.(type) {
+	case *ast.Identifier:
+		if pattern.Value == "_" {
+			return nil
+		}
+		return []string{pattern.Value}
+
+	case *ast.ArrayPattern:
+		names := []string{}
+		for _, element := range pattern.Elements {
+			names = append(names, patternNames(element)...)
+		}
+		if pattern.Rest != nil {
+			names = append(names, patternNames(pattern.Rest)...)
+		}
+		return names
+
+	case *ast.HashPattern:
+		names := []string{}
+		for _, pair := range pattern.Pairs {
+			names = append(names, patternNames(pair.Value)...)
+		}
+		return names
+
+	default:
+		return nil
+	}
+}
+
+func evalModuleIndexExpression(module, index object.Object) object.Object {
+	moduleObject := module.(*object.Module)
+	name := index.(*object.String).Value
+
+	val, ok := moduleObject.Exports[name]
+	if !ok {
+		return newError(object.NAME_ERROR, "module %q does not export %s",
+			moduleObject.Path, name)
+	}
+
+	return val
+}
+
+// moduleHash returns the exports of module as a hash keyed by name, in
+// alphabetical order, for destructuring with let {name} = import "...".
+func moduleHash(module *object.Module) *object.Hash {
+	names := make([]string, 0, len(module.Exports))
+	for name := range module.Exports {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	hash := &object.Hash{}
+	for _, name := range names {
+		key := &object.String{Value: name}
+		hash.Set(key.HashKey(), object.HashPair{Key: key, Value: module.Exports[name]})
+	}
+
+	return hash
+}
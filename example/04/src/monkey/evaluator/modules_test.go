@@ -0,0 +1,209 @@
+package evaluator
+
+import (
+	"io/ioutil"
+	"monkey/ast"
+	"monkey/lexer"
+	"monkey/object"
+	"monkey/parser"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// writeModules creates the given files in a new temporary directory and
+// returns its path.
+func writeModules(t *testing.T, files map[string]string) string {
+	dir, err := ioutil.TempDir("", "monkey-modules")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return dir
+}
+
+func testEvalFile(t *testing.T, filename, input string) object.Object {
+	p := parser.New(lexer.NewFile(filename, input))
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		t.Fatalf("parser errors: %v", p.Errors())
+	}
+
+	return Eval(program, object.NewEnvironment())
+}
+
+func TestImport(t *testing.T) {
+	dir := writeModules(t, map[string]string{
+		"math.monkey": `
+let hidden = 10;
+export let base = hidden * 2;
+export fn add(a, b) { a + b }
+export let [one, two] = [1, 2];
+`,
+		"lib/counter.monkey": `
+let count = 0;
+export let next = fn() { count += 1 };
+`,
+		"lib/uses_math.monkey": `
+let m = import "../math";
+export let three = m["add"](m["one"], m["two"]);
+`,
+	})
+	defer os.RemoveAll(dir)
+	main := filepath.Join(dir, "main.monkey")
+
+	tests := []struct {
+		input    string
+		expected int64
+	}{
+		{`let m = import "math"; m["add"](m["base"], 1)`, 21},
+		{`let m = import "math.monkey"; m["two"]`, 2},
+		{`let {add, one} = import "math"; add(one, 41)`, 42},
+		{`import "lib/uses_math"["three"]`, 3},
+		{`let a = import "lib/counter"; let b = import "./lib/counter";
+		  a["next"](); b["next"](); a["next"]()`, 3},
+	}
+
+	for _, tt := range tests {
+		testIntegerObject(t, testEvalFile(t, main, tt.input), tt.expected)
+	}
+
+	abs := testEvalFile(t, "", `import "`+filepath.Join(dir, "math")+`"`)
+	module, ok := abs.(*object.Module)
+	if !ok {
+		t.Fatalf("object is not Module. got=%T (%+v)", abs, abs)
+	}
+	if _, ok := module.Exports["hidden"]; ok {
+		t.Errorf("module exports unexported name hidden")
+	}
+	if len(module.Exports) != 4 {
+		t.Errorf("wrong number of exports. want=4, got=%d", len(module.Exports))
+	}
+}
+
+func TestImportCacheIsPerProgram(t *testing.T) {
+	dir := writeModules(t, map[string]string{
+		"counter.monkey": `
+let count = 0;
+export let next = fn() { count += 1 };
+`,
+	})
+	defer os.RemoveAll(dir)
+	main := filepath.Join(dir, "main.monkey")
+
+	parse := func(input string) *ast.Program {
+		p := parser.New(lexer.NewFile(main, input))
+		program := p.ParseProgram()
+		if len(p.Errors()) != 0 {
+			t.Fatalf("parser errors: %v", p.Errors())
+		}
+		return program
+	}
+	next := parse(`import "counter"["next"]()`)
+
+	first := object.NewEnvironment()
+	testIntegerObject(t, Eval(next, first), 1)
+	testIntegerObject(t, Eval(next, first), 2)
+
+	second := object.NewEnvironment()
+	testIntegerObject(t, Eval(next, second), 1)
+	testIntegerObject(t, Eval(next, first), 3)
+
+	enclosed := object.NewEnclosedEnvironment(second)
+	testIntegerObject(t, Eval(next, enclosed), 2)
+
+	if len(first.Imports().Importing) != 0 {
+		t.Errorf("modules still being imported: %v", first.Imports().Importing)
+	}
+}
+
+func TestImportSharesOptions(t *testing.T) {
+	dir := writeModules(t, map[string]string{
+		"max.monkey": `export let inc = fn(x) { x + 1 };`,
+	})
+	defer os.RemoveAll(dir)
+
+	p := parser.New(lexer.NewFile(filepath.Join(dir, "main.monkey"),
+		`import "max"["inc"](9223372036854775807)`))
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		t.Fatalf("parser errors: %v", p.Errors())
+	}
+
+	env := object.NewEnvironment()
+	env.Options().CheckedArithmetic = true
+
+	errObj, ok := Eval(program, env).(*object.Error)
+	if !ok {
+		t.Fatalf("no error object returned")
+	}
+	if errObj.Message != "integer overflow: 9223372036854775807 + 1" {
+		t.Errorf("wrong error message. got=%q", errObj.Message)
+	}
+}
+
+func TestImportErrors(t *testing.T) {
+	dir := writeModules(t, map[string]string{
+		"a.monkey":      `export let x = import "b";`,
+		"b.monkey":      `export let y = import "a";`,
+		"bad.monkey":    `let = 1;`,
+		"broken.monkey": `export let z = 1 + true;`,
+		"ok.monkey":     `export let v = 1;`,
+		"macro.monkey":  "let m = macro(a) { 1 };\nexport let w = m(1);",
+	})
+	defer os.RemoveAll(dir)
+	main := filepath.Join(dir, "main.monkey")
+
+	tests := []struct {
+		input           string
+		expectedKind    string
+		expectedMessage string
+	}{
+		{`import "a"`, object.IMPORT_ERROR, "circular import: " +
+			strings.Join([]string{
+				filepath.Join(dir, "a.monkey"),
+				filepath.Join(dir, "b.monkey"),
+				filepath.Join(dir, "a.monkey"),
+			}, " -> ")},
+		{`import "missing"`, object.IMPORT_ERROR, "cannot import \"" +
+			filepath.Join(dir, "missing.monkey") + "\": open " +
+			filepath.Join(dir, "missing.monkey") + ": no such file or directory"},
+		{`import "bad"`, object.IMPORT_ERROR, "cannot import \"" +
+			filepath.Join(dir, "bad.monkey") + "\": " +
+			filepath.Join(dir, "bad.monkey") + ":1:5: expected next token to be IDENT, got = instead"},
+		{`import "broken"`, object.TYPE_ERROR, "type mismatch: INTEGER + BOOLEAN"},
+		{`import "macro"`, object.TYPE_ERROR, "macro m must return a quote, got INTEGER"},
+		{`import "ok"["w"]`, object.NAME_ERROR, "module \"" +
+			filepath.Join(dir, "ok.monkey") + "\" does not export w"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEvalFile(t, main, tt.input)
+
+		errObj, ok := evaluated.(*object.Error)
+		if !ok {
+			t.Errorf("no error object returned. got=%T(%+v)", evaluated, evaluated)
+			continue
+		}
+
+		if errObj.Kind != tt.expectedKind {
+			t.Errorf("wrong error kind. expected=%q, got=%q", tt.expectedKind, errObj.Kind)
+		}
+
+		if errObj.Message != tt.expectedMessage {
+			t.Errorf("wrong error message. expected=%q, got=%q",
+				tt.expectedMessage, errObj.Message)
+		}
+	}
+}
@@ -0,0 +1,81 @@
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"monkey/format"
+	"os"
+)
+
+// runFmt implements "monkey fmt [-w] [-check] [files]", which prints the
+// files, or standard input, in canonical form.
+func runFmt(args []string) int {
+	flags := flag.NewFlagSet("fmt", flag.ExitOnError)
+	write := flags.Bool("w", false,
+		"write the result to the file instead of standard output")
+	check := flags.Bool("check", false,
+		"list the files that are not formatted and exit with status 1 if there are any")
+	flags.Parse(args)
+
+	if flags.NArg() == 0 {
+		if *write {
+			fmt.Fprintln(os.Stderr, "cannot use -w with standard input")
+			return 2
+		}
+
+		input, err := ioutil.ReadAll(os.Stdin)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		return formatSource("<stdin>", input, false, *check)
+	}
+
+	status := 0
+	for _, filename := range flags.Args() {
+		input, err := ioutil.ReadFile(filename)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			status = 1
+			continue
+		}
+
+		if s := formatSource(filename, input, *write, *check); s != 0 {
+			status = s
+		}
+	}
+	return status
+}
+
+func formatSource(filename string, input []byte, write, check bool) int {
+	output, err := format.Source(filename, input)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	switch {
+	case check:
+		if !bytes.Equal(input, output) {
+			fmt.Println(filename)
+			return 1
+		}
+	case write:
+		if bytes.Equal(input, output) {
+			return 0
+		}
+		info, err := os.Stat(filename)
+		if err == nil {
+			err = ioutil.WriteFile(filename, output, info.Mode().Perm())
+		}
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+	default:
+		os.Stdout.Write(output)
+	}
+	return 0
+}
@@ -0,0 +1,730 @@
+// Package format implements the canonical formatting of Monkey source code.
+package format
+
+import (
+	"bytes"
+	"fmt"
+	"monkey/ast"
+	"monkey/lexer"
+	"monkey/parser"
+	"monkey/token"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+const indentation = "  "
+
+// primary is the precedence of expressions that never need parentheses, such
+// as literals and identifiers.
+const primary = parser.INDEX + 1
+
+// Source parses src and returns it in canonical form. Comments are kept. The
+// first parse error is returned if src is not a valid program. Source that is
+// not valid UTF-8 is refused, because string literals cannot spell out its
+// bytes, so formatting would change the program.
+func Source(filename string, src []byte) ([]byte, error) {
+	if pos, ok := invalidUTF8(filename, src); ok {
+		return nil, fmt.Errorf("%s: invalid UTF-8 encoding", pos)
+	}
+
+	l := lexer.NewFile(filename, string(src))
+	l.ScanComments()
+	p := parser.New(l)
+
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		return nil, p.Errors()[0]
+	}
+
+	return []byte(Program(program)), nil
+}
+
+// invalidUTF8 returns the position of the first byte of src that is not valid
+// UTF-8, if there is one.
+func invalidUTF8(filename string, src []byte) (token.Position, bool) {
+	pos := token.Position{Filename: filename, Line: 1, Column: 1}
+
+	for pos.Offset < len(src) {
+		r, size := utf8.DecodeRune(src[pos.Offset:])
+		if r == utf8.RuneError && size == 1 {
+			return pos, true
+		}
+
+		if r == '\n' {
+			pos.Line++
+			pos.Column = 1
+		} else {
+			pos.Column++
+		}
+		pos.Offset += size
+	}
+
+	return pos, false
+}
+
+// Program renders program in canonical form: one statement per line, blocks
+// indented by two spaces, and only the parentheses the parser needs. The
+// comments in program.Comments are placed before the statement they precede,
+// or at the end of the line they were on. Array and hash literals and call
+// arguments that contain comments are printed with one item per line, so that
+// their comments stay in place.
+func Program(program *ast.Program) string {
+	p := &printer{comments: program.Comments}
+
+	p.statements(program.Statements)
+	for len(p.comments) > 0 {
+		p.comment()
+	}
+
+	return p.out.String()
+}
+
+type printer struct {
+	out      bytes.Buffer
+	indent   int
+	comments []*ast.Comment // the comments not printed yet
+	line     int            // source line of the last thing printed, 0 at the start of a block
+}
+
+// render returns what print writes to a printer without comments, for
+// deciding how to lay out the surroundings of a node.
+func render(print func(p *printer)) string {
+	p := &printer{}
+	print(p)
+	return p.out.String()
+}
+
+func (p *printer) print(a ...interface{}) {
+	for _, x := range a {
+		fmt.Fprint(&p.out, x)
+	}
+}
+
+// separate starts a new line for something found at pos, keeping a single
+// blank line if the source had one or more before it.
+func (p *printer) separate(pos token.Position) {
+	if p.line > 0 && pos.IsValid() && pos.Line > p.line+1 {
+		p.out.WriteByte('\n')
+	}
+	p.out.WriteString(strings.Repeat(indentation, p.indent))
+}
+
+// commentsBefore prints the pending comments that start before pos on lines of
+// their own.
+func (p *printer) commentsBefore(pos token.Position) {
+	if !pos.IsValid() {
+		return
+	}
+
+	for len(p.comments) > 0 && p.comments[0].Pos().Offset < pos.Offset {
+		p.comment()
+	}
+}
+
+// comment prints the next pending comment on a line of its own.
+func (p *printer) comment() {
+	c := p.comments[0]
+	p.comments = p.comments[1:]
+
+	p.separate(c.Pos())
+	p.print(c.Token.Literal, "\n")
+	p.line = c.End().Line
+}
+
+// trailingComment prints the next pending comment if it follows end on the
+// same line.
+func (p *printer) trailingComment(end token.Position) {
+	if len(p.comments) == 0 || !end.IsValid() {
+		return
+	}
+
+	c := p.comments[0]
+	if c.Pos().Line == end.Line && c.Pos().Offset >= end.Offset {
+		p.print(" ", c.Token.Literal)
+		p.line = c.End().Line
+		p.comments = p.comments[1:]
+	}
+}
+
+// hasComments reports whether a pending comment lies between start and end.
+func (p *printer) hasComments(start, end token.Position) bool {
+	if !start.IsValid() || !end.IsValid() {
+		return false
+	}
+
+	for _, c := range p.comments {
+		if c.Pos().Offset >= start.Offset && c.Pos().Offset < end.Offset {
+			return true
+		}
+	}
+	return false
+}
+
+func (p *printer) statements(stmts []ast.Statement) {
+	for i, stmt := range stmts {
+		p.commentsBefore(stmt.Pos())
+		p.separate(stmt.Pos())
+		p.statement(stmt)
+
+		var next ast.Statement
+		if i+1 < len(stmts) {
+			next = stmts[i+1]
+		}
+		if needsSemicolon(stmt, next) {
+			p.print(";")
+		}
+
+		p.trailingComment(stmt.End())
+		p.print("\n")
+		if end := stmt.End(); end.IsValid() && end.Line > p.line {
+			p.line = end.Line
+		}
+	}
+}
+
+// needsSemicolon reports whether stmt has to be terminated by a semicolon
+// when it is followed by next. Expression statements ending in a block, such
+// as if expressions, only need one if next would otherwise continue them.
+func needsSemicolon(stmt, next ast.Statement) bool {
+	switch stmt := stmt.(type) {
+	case *ast.ExportStatement:
+		return needsSemicolon(stmt.Statement, next)
+	case *ast.FunctionStatement, *ast.WhileStatement, *ast.ForStatement:
+		return false
+	case *ast.ExpressionStatement:
+		switch stmt.Expression.(type) {
+		case *ast.IfExpression, *ast.TryExpression, *ast.MatchExpression:
+		default:
+			return true
+		}
+
+		if next == nil {
+			return false
+		}
+		if _, ok := next.(*ast.ExpressionStatement); !ok {
+			return false
+		}
+		text := render(func(p *printer) { p.statement(next) })
+		return strings.HasPrefix(text, "(") || strings.HasPrefix(text, "[") ||
+			strings.HasPrefix(text, "-")
+	default:
+		return true
+	}
+}
+
+func (p *printer) statement(stmt ast.Statement) {
+	switch stmt := stmt.(type) {
+	case *ast.LetStatement:
+		p.print("let ")
+		if stmt.Pattern != nil {
+			p.pattern(stmt.Pattern)
+		} else {
+			p.print(stmt.Name.Value)
+		}
+		p.print(" = ")
+		p.expression(stmt.Value, parser.LOWEST)
+
+	case *ast.ReturnStatement:
+		p.print("return")
+		if stmt.ReturnValue != nil {
+			p.print(" ")
+			p.expression(stmt.ReturnValue, parser.LOWEST)
+		}
+
+	case *ast.ThrowStatement:
+		p.print("throw ")
+		p.expression(stmt.Value, parser.LOWEST)
+
+	case *ast.ExportStatement:
+		p.print("export ")
+		p.statement(stmt.Statement)
+
+	case *ast.FunctionStatement:
+		p.print("fn ", stmt.Name.Value)
+		p.parameters(stmt.Function)
+		p.print(" ")
+		p.block(stmt.Function.Body, false)
+
+	case *ast.ExpressionStatement:
+		p.expression(stmt.Expression, parser.LOWEST)
+
+	case *ast.WhileStatement:
+		p.print("while (")
+		p.expression(stmt.Condition, parser.LOWEST)
+		p.print(") ")
+		p.block(stmt.Body, false)
+
+	case *ast.ForStatement:
+		p.print("for (", stmt.Variable.Value, " in ")
+		p.expression(stmt.Iterable, parser.LOWEST)
+		p.print(") ")
+		p.block(stmt.Body, false)
+
+	case *ast.BreakStatement:
+		p.print("break")
+
+	case *ast.ContinueStatement:
+		p.print("continue")
+
+	case *ast.BlockStatement:
+		p.block(stmt, false)
+	}
+}
+
+// block prints a braced block. If inline is set, a block holding a single
+// expression is kept on one line.
+func (p *printer) block(block *ast.BlockStatement, inline bool) {
+	if len(block.Statements) == 0 && !p.hasComments(block.Pos(), block.End()) {
+		p.print("{}")
+		return
+	}
+
+	if inline && len(block.Statements) == 1 && !p.hasComments(block.Pos(), block.End()) {
+		if stmt, ok := block.Statements[0].(*ast.ExpressionStatement); ok {
+			text := render(func(p *printer) { p.expression(stmt.Expression, parser.LOWEST) })
+			if !strings.Contains(text, "\n") {
+				p.print("{ ", text, " }")
+				return
+			}
+		}
+	}
+
+	p.print("{\n")
+	p.indent++
+	p.line = 0
+
+	p.statements(block.Statements)
+	p.commentsBefore(block.Rbrace.Pos)
+
+	p.indent--
+	p.print(strings.Repeat(indentation, p.indent), "}")
+}
+
+// precedence returns how tightly expression binds, using the parser's
+// precedence levels.
+func precedence(expression ast.Expression) int {
+	switch expression := expression.(type) {
+	case *ast.AssignExpression:
+		return parser.ASSIGNMENT
+	case *ast.InfixExpression:
+		return parser.Precedence(expression.Token.Type)
+	case *ast.PrefixExpression:
+		return parser.PREFIX
+	case *ast.CallExpression:
+		return parser.CALL
+	case *ast.IndexExpression:
+		return parser.INDEX
+	default:
+		return primary
+	}
+}
+
+// expression prints expression, wrapped in parentheses if it binds less
+// tightly than prec.
+func (p *printer) expression(expression ast.Expression, prec int) {
+	if precedence(expression) < prec {
+		p.print("(")
+		defer p.print(")")
+	}
+
+	switch expression := expression.(type) {
+	case *ast.Identifier:
+		p.print(expression.Value)
+
+	case *ast.Boolean:
+		p.print(strconv.FormatBool(expression.Value))
+
+	case *ast.IntegerLiteral:
+		if expression.Token.Type == token.INT {
+			p.print(expression.Token.Literal)
+		} else if expression.Big != nil {
+			p.print(expression.Big.String())
+		} else {
+			p.print(strconv.FormatInt(expression.Value, 10))
+		}
+
+	case *ast.FloatLiteral:
+		if expression.Token.Type == token.FLOAT {
+			p.print(expression.Token.Literal)
+		} else {
+			p.print(formatFloat(expression.Value))
+		}
+
+	case *ast.StringLiteral:
+		p.print(`"`, escape(expression.Value), `"`)
+
+	case *ast.TemplateLiteral:
+		p.print(`"`)
+		for i, part := range expression.Parts {
+			if i%2 == 0 {
+				p.print(escape(part.(*ast.StringLiteral).Value))
+				continue
+			}
+			p.print("${")
+			p.expression(part, parser.LOWEST)
+			p.print("}")
+		}
+		p.print(`"`)
+
+	case *ast.PrefixExpression:
+		p.print(expression.Operator)
+		p.expression(expression.Right, parser.PREFIX)
+
+	case *ast.InfixExpression:
+		prec := parser.Precedence(expression.Token.Type)
+		p.expression(expression.Left, prec)
+		p.print(" ", expression.Operator, " ")
+		p.expression(expression.Right, prec+1)
+
+	case *ast.AssignExpression:
+		p.expression(expression.Target, parser.CALL)
+		p.print(" ", expression.Operator, " ")
+		p.expression(expression.Value, parser.ASSIGNMENT)
+
+	case *ast.IfExpression:
+		p.ifExpression(expression)
+
+	case *ast.TryExpression:
+		p.print("try ")
+		p.block(expression.Block, false)
+		if expression.Catch != nil {
+			p.print(" catch ")
+			if expression.Param != nil {
+				p.print("(", expression.Param.Value, ") ")
+			}
+			p.block(expression.Catch, false)
+		}
+		if expression.Finally != nil {
+			p.print(" finally ")
+			p.block(expression.Finally, false)
+		}
+
+	case *ast.MatchExpression:
+		p.matchExpression(expression)
+
+	case *ast.FunctionLiteral:
+		p.print("fn")
+		p.parameters(expression)
+		p.print(" ")
+		p.block(expression.Body, true)
+
+	case *ast.MacroLiteral:
+		p.print("macro")
+		p.identifiers(expression.Parameters)
+		p.print(" ")
+		p.block(expression.Body, true)
+
+	case *ast.ImportExpression:
+		p.print("import ")
+		p.expression(expression.Path, parser.LOWEST)
+
+	case *ast.CallExpression:
+		p.expression(expression.Function, parser.CALL)
+		p.callArguments(expression)
+
+	case *ast.IndexExpression:
+		p.expression(expression.Left, parser.CALL)
+		p.print("[")
+		p.expression(expression.Index, parser.LOWEST)
+		p.print("]")
+
+	case *ast.ArrayLiteral:
+		p.list("[", "]", expression.Pos(), expression.End(), len(expression.Elements),
+			func(i int) (token.Position, token.Position) {
+				return expression.Elements[i].Pos(), expression.Elements[i].End()
+			},
+			func(i int) { p.expression(expression.Elements[i], parser.LOWEST) })
+
+	case *ast.HashLiteral:
+		p.hashLiteral(expression)
+	}
+}
+
+func (p *printer) ifExpression(ie *ast.IfExpression) {
+	p.print("if (")
+	p.expression(ie.Condition, parser.LOWEST)
+	p.print(") ")
+	p.block(ie.Consequence, true)
+
+	if ie.Alternative == nil {
+		return
+	}
+
+	p.print(" else ")
+	if elseIf, ok := elseIfExpression(ie.Alternative); ok {
+		p.ifExpression(elseIf)
+		return
+	}
+	p.block(ie.Alternative, true)
+}
+
+// elseIfExpression returns the if expression of an else-if alternative, which
+// the parser wraps in a block of its own.
+func elseIfExpression(block *ast.BlockStatement) (*ast.IfExpression, bool) {
+	if block.Token.Type != token.IF || len(block.Statements) != 1 {
+		return nil, false
+	}
+
+	stmt, ok := block.Statements[0].(*ast.ExpressionStatement)
+	if !ok {
+		return nil, false
+	}
+
+	ie, ok := stmt.Expression.(*ast.IfExpression)
+	return ie, ok
+}
+
+func (p *printer) matchExpression(me *ast.MatchExpression) {
+	p.print("match (")
+	p.expression(me.Subject, parser.LOWEST)
+	p.print(") {\n")
+	p.indent++
+	p.line = 0
+
+	for _, arm := range me.Arms {
+		p.commentsBefore(arm.Pattern.Pos())
+		p.separate(arm.Pattern.Pos())
+		p.pattern(arm.Pattern)
+		p.print(" => ")
+
+		if arm.Body.Token.Type == token.LBRACE {
+			p.block(arm.Body, true)
+		} else {
+			value := arm.Body.Statements[0].(*ast.ExpressionStatement).Expression
+			// a { after => starts a block, so a hash needs parentheses
+			if _, ok := value.(*ast.HashLiteral); ok {
+				p.print("(")
+				p.expression(value, parser.LOWEST)
+				p.print(")")
+			} else {
+				p.expression(value, parser.LOWEST)
+			}
+		}
+
+		p.print(",")
+		p.trailingComment(arm.Body.End())
+		p.print("\n")
+		if end := arm.Body.End(); end.IsValid() && end.Line > p.line {
+			p.line = end.Line
+		}
+	}
+
+	p.commentsBefore(me.Rbrace.Pos)
+
+	p.indent--
+	p.print(strings.Repeat(indentation, p.indent), "}")
+}
+
+func (p *printer) hashLiteral(hl *ast.HashLiteral) {
+	p.list("{", "}", hl.Pos(), hl.End(), len(hl.Pairs),
+		func(i int) (token.Position, token.Position) {
+			return hl.Pairs[i].Key.Pos(), hl.Pairs[i].Value.End()
+		},
+		func(i int) {
+			p.expression(hl.Pairs[i].Key, parser.LOWEST)
+			p.print(": ")
+			p.expression(hl.Pairs[i].Value, parser.LOWEST)
+		})
+}
+
+// callArguments prints the positional arguments of ce followed by its keyword
+// arguments.
+func (p *printer) callArguments(ce *ast.CallExpression) {
+	n := len(ce.Arguments)
+	p.list("(", ")", ce.Token.Pos, ce.End(), n+len(ce.Keywords),
+		func(i int) (token.Position, token.Position) {
+			if i < n {
+				return ce.Arguments[i].Pos(), ce.Arguments[i].End()
+			}
+			return ce.Keywords[i-n].Name.Pos(), ce.Keywords[i-n].Value.End()
+		},
+		func(i int) {
+			if i < n {
+				p.expression(ce.Arguments[i], parser.LOWEST)
+				return
+			}
+			p.print(ce.Keywords[i-n].Name.Value, ": ")
+			p.expression(ce.Keywords[i-n].Value, parser.LOWEST)
+		})
+}
+
+// list prints the n items of a comma-separated list between open and close,
+// which are found at start and end in the source. span returns where item i
+// is and print prints it. The items go on one line unless comments lie
+// within the list, in which case every item gets a line of its own so that
+// the comments stay next to them.
+func (p *printer) list(
+	open, close string,
+	start, end token.Position,
+	n int,
+	span func(i int) (token.Position, token.Position),
+	print func(i int),
+) {
+	p.print(open)
+
+	if !p.hasComments(start, end) {
+		for i := 0; i < n; i++ {
+			if i > 0 {
+				p.print(", ")
+			}
+			print(i)
+		}
+		p.print(close)
+		return
+	}
+
+	p.print("\n")
+	p.indent++
+	p.line = 0
+
+	for i := 0; i < n; i++ {
+		itemStart, itemEnd := span(i)
+		p.commentsBefore(itemStart)
+		p.separate(itemStart)
+		print(i)
+
+		// a comment belongs to the next item if it comes after its start
+		next := end
+		if i < n-1 {
+			p.print(",")
+			next, _ = span(i + 1)
+		}
+		if len(p.comments) > 0 && p.comments[0].Pos().Offset < next.Offset {
+			p.trailingComment(itemEnd)
+		}
+		p.print("\n")
+		if itemEnd.IsValid() && itemEnd.Line > p.line {
+			p.line = itemEnd.Line
+		}
+	}
+	p.commentsBefore(end)
+
+	p.indent--
+	p.print(strings.Repeat(indentation, p.indent), close)
+}
+
+func (p *printer) parameters(fl *ast.FunctionLiteral) {
+	p.print("(")
+	for i, param := range fl.Parameters {
+		if i > 0 {
+			p.print(", ")
+		}
+		p.print(param.Value)
+		if def, ok := fl.Defaults[param.Value]; ok {
+			p.print(" = ")
+			p.expression(def, parser.LOWEST)
+		}
+	}
+	if fl.Rest != nil {
+		if len(fl.Parameters) > 0 {
+			p.print(", ")
+		}
+		p.print("...", fl.Rest.Value)
+	}
+	p.print(")")
+}
+
+func (p *printer) identifiers(idents []*ast.Identifier) {
+	p.print("(")
+	for i, ident := range idents {
+		if i > 0 {
+			p.print(", ")
+		}
+		p.print(ident.Value)
+	}
+	p.print(")")
+}
+
+func (p *printer) pattern(pattern ast.Pattern) {
+	switch pattern := pattern.(type) {
+	case *ast.Identifier:
+		p.print(pattern.Value)
+
+	case *ast.LiteralPattern:
+		p.expression(pattern.Value, parser.LOWEST)
+
+	case *ast.ArrayPattern:
+		p.print("[")
+		for i, el := range pattern.Elements {
+			if i > 0 {
+				p.print(", ")
+			}
+			p.pattern(el)
+		}
+		if pattern.Rest != nil {
+			if len(pattern.Elements) > 0 {
+				p.print(", ")
+			}
+			p.print("...", pattern.Rest.Value)
+		}
+		p.print("]")
+
+	case *ast.HashPattern:
+		p.print("{")
+		for i, pair := range pattern.Pairs {
+			if i > 0 {
+				p.print(", ")
+			}
+
+			key, ok := pair.Key.(*ast.StringLiteral)
+			if !ok || !isIdentifier(key.Value) {
+				p.expression(pair.Key, parser.LOWEST)
+				p.print(": ")
+				p.pattern(pair.Value)
+				continue
+			}
+
+			p.print(key.Value)
+			if ident, ok := pair.Value.(*ast.Identifier); !ok || ident.Value != key.Value {
+				p.print(": ")
+				p.pattern(pair.Value)
+			}
+		}
+		p.print("}")
+	}
+}
+
+// isIdentifier reports whether s is lexed as a single identifier. It asks the
+// lexer, so that keywords and characters the lexer does not allow in
+// identifiers, such as digits, are rejected.
+func isIdentifier(s string) bool {
+	l := lexer.New(s)
+	tok := l.NextToken()
+	return tok.Type == token.IDENT && tok.Literal == s && l.NextToken().Type == token.EOF
+}
+
+// escape quotes s for use between the double quotes of a string literal.
+func escape(s string) string {
+	var out strings.Builder
+
+	for i, ch := range s {
+		switch {
+		case ch == '"' || ch == '\\':
+			out.WriteByte('\\')
+			out.WriteRune(ch)
+		case ch == '\n':
+			out.WriteString(`\n`)
+		case ch == '\t':
+			out.WriteString(`\t`)
+		case ch == '\r':
+			out.WriteString(`\r`)
+		case ch == '$' && strings.HasPrefix(s[i+1:], "{"):
+			out.WriteString(`\$`)
+		case ch < ' ' || ch == 0x7f:
+			fmt.Fprintf(&out, `\u%04x`, ch)
+		default:
+			out.WriteRune(ch)
+		}
+	}
+
+	return out.String()
+}
+
+func formatFloat(f float64) string {
+	s := strconv.FormatFloat(f, 'g', -1, 64)
+	if !strings.ContainsAny(s, ".eEIN") {
+		s += ".0"
+	}
+	return s
+}
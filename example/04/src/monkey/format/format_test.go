@@ -0,0 +1,278 @@
+package format
+
+import (
+	"monkey/lexer"
+	"monkey/parser"
+	"testing"
+)
+
+func TestSource(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"let x=5", "let x = 5;\n"},
+		{"let x = (1 + 2) * 3;", "let x = (1 + 2) * 3;\n"},
+		{"let x = 1 + (2 * 3);", "let x = 1 + 2 * 3;\n"},
+		{"(1 - 2) - (3 - 4);", "1 - 2 - (3 - 4);\n"},
+		{"a + (b + c);", "a + (b + c);\n"},
+		{"(a || b) && !(c == d);", "(a || b) && !(c == d);\n"},
+		{"-(a * b);", "-(a * b);\n"},
+		{"(-a)[0]; -a[0];", "(-a)[0];\n-a[0];\n"},
+		{"(a + b)(c);", "(a + b)(c);\n"},
+		{"f(1, b: 2)[1](3);", "f(1, b: 2)[1](3);\n"},
+		{"x = (y = 3);", "x = y = 3;\n"},
+		{"(x = 1) + 2;", "(x = 1) + 2;\n"},
+		{"a[0] += 1;", "a[0] += 1;\n"},
+		{"0x1F + 1_000 * 1.5e3;", "0x1F + 1_000 * 1.5e3;\n"},
+		{`{"b": 1, "a": [2], 3: {}};`, `{"b": 1, "a": [2], 3: {}};` + "\n"},
+		{`let {"a": p, "a1": q, "if": r, "é": s} = h;`, `let {a: p, "a1": q, "if": r, é: s} = h;` + "\n"},
+		{
+			`"say \"hi\"\n" + ` + "`${raw}`;",
+			`"say \"hi\"\n" + "\${raw}";` + "\n",
+		},
+		{`"${a + 1} and ${b}!";`, `"${a + 1} and ${b}!";` + "\n"},
+		{"let f = fn(a, b = 2, ...rest) { a + b };", "let f = fn(a, b = 2, ...rest) { a + b };\n"},
+		{
+			"let f = fn(x) { let y = x; y };",
+			"let f = fn(x) {\n  let y = x;\n  y;\n};\n",
+		},
+		{"fn f() {}", "fn f() {}\n"},
+		{
+			"fn f(x) { return x; }",
+			"fn f(x) {\n  return x;\n}\n",
+		},
+		{
+			"export fn f() { 1 }",
+			"export fn f() {\n  1;\n}\n",
+		},
+		{`export let m = import "lib";`, "export let m = import \"lib\";\n"},
+		{
+			"if (x) { 1 } else if (y) { 2 } else { 3 }",
+			"if (x) { 1 } else if (y) { 2 } else { 3 }\n",
+		},
+		{
+			"if (x) { 1 }; (y);",
+			"if (x) { 1 }\ny;\n",
+		},
+		{
+			"if (x) { 1 }; (a + b) * c;",
+			"if (x) { 1 };\n(a + b) * c;\n",
+		},
+		{
+			"if (x) { 1 }; -1;",
+			"if (x) { 1 };\n-1;\n",
+		},
+		{
+			"if (x) { 1 }; [y];",
+			"if (x) { 1 };\n[y];\n",
+		},
+		{
+			"while (x < 3) { x += 1; if (x == 2) { break; } }",
+			"while (x < 3) {\n  x += 1;\n  if (x == 2) {\n    break;\n  }\n}\n",
+		},
+		{
+			"for (x in [1,2]) { continue; }",
+			"for (x in [1, 2]) {\n  continue;\n}\n",
+		},
+		{
+			"try { throw \"x\"; } catch (e) { e } finally { done(); }",
+			"try {\n  throw \"x\";\n} catch (e) {\n  e;\n} finally {\n  done();\n}\n",
+		},
+		{
+			`match (x) { 1 => "one", -2 => { two }, [a, ...r] => a, {k, "v": [w]} => ({"a": w}), _ => 0 }`,
+			"match (x) {\n  1 => \"one\",\n  -2 => { two },\n  [a, ...r] => a,\n  {k, v: [w]} => ({\"a\": w}),\n  _ => 0,\n}\n",
+		},
+		{
+			`let [a, [b], ...c] = x; let {name, "first name": n} = y;`,
+			"let [a, [b], ...c] = x;\nlet {name, \"first name\": n} = y;\n",
+		},
+		{
+			"let unless = macro(c, a) { quote(if (!(unquote(c))) { unquote(a) }) };",
+			"let unless = macro(c, a) { quote(if (!unquote(c)) { unquote(a) }) };\n",
+		},
+	}
+
+	for _, tt := range tests {
+		output, err := Source("", []byte(tt.input))
+		if err != nil {
+			t.Errorf("Source(%q) returned error: %s", tt.input, err)
+			continue
+		}
+
+		if string(output) != tt.expected {
+			t.Errorf("Source(%q) wrong.\nexpected=%q\ngot=%q", tt.input, tt.expected, output)
+		}
+
+		if parse(t, tt.input) != parse(t, string(output)) {
+			t.Errorf("Source(%q) changed the program. got=%q", tt.input, output)
+		}
+	}
+}
+
+func TestSourceComments(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{
+			`// Package comment.
+
+
+let x = 1;   // one
+/* two */ let y = 2;
+let f = fn() {
+
+  // inside
+
+
+  x;
+  // last
+};
+
+
+
+// end
+`,
+			`// Package comment.
+
+let x = 1; // one
+/* two */
+let y = 2;
+let f = fn() {
+  // inside
+
+  x;
+  // last
+};
+
+// end
+`,
+		},
+		{
+			`let h = {
+  // first
+  "a": 1, // one
+
+  "b": [2, // two
+        3]
+};
+let z = [1, 2 /* last */];
+`,
+			`let h = {
+  // first
+  "a": 1, // one
+
+  "b": [
+    2, // two
+    3
+  ]
+};
+let z = [
+  1,
+  2 /* last */
+];
+`,
+		},
+		{
+			`puts(x, // the value
+  // keywords follow
+  sep: ", "
+); // done
+f(/* nothing */);
+`,
+			`puts(
+  x, // the value
+  // keywords follow
+  sep: ", "
+); // done
+f(
+  /* nothing */
+);
+`,
+		},
+	}
+
+	for _, tt := range tests {
+		output, err := Source("", []byte(tt.input))
+		if err != nil {
+			t.Fatalf("Source returned error: %s", err)
+		}
+
+		if string(output) != tt.expected {
+			t.Errorf("wrong output.\nexpected=%q\ngot=%q", tt.expected, output)
+		}
+
+		again, err := Source("", output)
+		if err != nil {
+			t.Fatalf("Source returned error for its own output: %s\n%s", err, output)
+		}
+		if string(again) != string(output) {
+			t.Errorf("formatting is not stable.\nfirst=%q\nsecond=%q", output, again)
+		}
+	}
+}
+
+func TestSourceIsStable(t *testing.T) {
+	input := `
+let fib = fn(n) { if (n < 2) { n } else { fib(n-1)+fib(n - 2) } };
+fn apply(f, ...args) { // calls f
+  let [first, ...rest] = args;
+  return f(first, rest: rest);
+}
+let counter = 0;
+while (counter < 10) { counter += 1 }
+let result = try { apply(fib, 10) } catch (e) { e["message"] };
+match (result) { 55 => puts("ok"), _ => puts("not ok: ${result}") }
+`
+
+	first, err := Source("", []byte(input))
+	if err != nil {
+		t.Fatalf("Source returned error: %s", err)
+	}
+
+	second, err := Source("", first)
+	if err != nil {
+		t.Fatalf("Source returned error for its own output: %s\n%s", err, first)
+	}
+
+	if string(first) != string(second) {
+		t.Errorf("formatting is not stable.\nfirst=%q\nsecond=%q", first, second)
+	}
+
+	if parse(t, input) != parse(t, string(first)) {
+		t.Errorf("formatting changed the program.\ninput=%q\noutput=%q", input, first)
+	}
+}
+
+func TestSourceError(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"let x = ;", "test.monkey:1:9: no prefix parse function for ; found"},
+		{"let é = 1;\nlet s = `\xff`;", "test.monkey:2:10: invalid UTF-8 encoding"},
+		{"let s = \"\xc3\";", "test.monkey:1:10: invalid UTF-8 encoding"},
+	}
+
+	for _, tt := range tests {
+		_, err := Source("test.monkey", []byte(tt.input))
+		if err == nil {
+			t.Errorf("expected an error for %q", tt.input)
+			continue
+		}
+
+		if err.Error() != tt.expected {
+			t.Errorf("wrong error. expected=%q, got=%q", tt.expected, err.Error())
+		}
+	}
+}
+
+func parse(t *testing.T, input string) string {
+	p := parser.New(lexer.New(input))
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		t.Fatalf("parser had errors: %v", p.Errors())
+	}
+	return program.String()
+}
@@ -1,24 +1,73 @@
 package lexer
 
-import "monkey/token"
+import (
+	"monkey/token"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
 
 type Lexer struct {
+	filename     string
 	input        string
 	position     int  // current position in input (points to current char)
 	readPosition int  // current reading position in input (after current char)
-	ch           byte // current char under examination
+	ch           rune // current char under examination
+	line         int  // line of the current char
+	column       int  // column of the current char, counted in runes
+
+	scanComments bool // emit COMMENT tokens instead of skipping comments
+
+	templates []template // strings whose "${" expressions are being read
+}
+
+// template tracks an interpolated expression inside a string literal. braces
+// counts the unclosed '{' within the expression so that the matching '}' can
+// be told apart from the one that resumes the string.
+type template struct {
+	textBlock bool
+	braces    int
 }
 
 func New(input string) *Lexer {
-	l := &Lexer{input: input}
+	return NewFile("", input)
+}
+
+func NewFile(filename, input string) *Lexer {
+	l := &Lexer{filename: filename, input: input, line: 1}
 	l.readChar()
 	return l
 }
 
+// ScanComments makes the lexer emit COMMENT tokens for line and block comments
+// instead of skipping them, so tooling can keep them.
+func (l *Lexer) ScanComments() {
+	l.scanComments = true
+}
+
 func (l *Lexer) NextToken() token.Token {
-	var tok token.Token
+	for {
+		l.skipWhitespace()
 
-	l.skipWhitespace()
+		pos := l.pos()
+		tok := l.readToken()
+		tok.Pos = pos
+		tok.End = l.pos()
+		if tok.Type == token.EOF {
+			tok.End = pos
+		}
+
+		if tok.Type == token.COMMENT && !l.scanComments {
+			continue
+		}
+
+		return tok
+	}
+}
+
+func (l *Lexer) readToken() token.Token {
+	var tok token.Token
 
 	switch l.ch {
 	case '=':
@@ -27,13 +76,23 @@ func (l *Lexer) NextToken() token.Token {
 			l.readChar()
 			literal := string(ch) + string(l.ch)
 			tok = token.Token{Type: token.EQ, Literal: literal}
+		} else if l.peekChar() == '>' {
+			tok = l.newTwoCharToken(token.ARROW)
 		} else {
 			tok = newToken(token.ASSIGN, l.ch)
 		}
 	case '+':
-		tok = newToken(token.PLUS, l.ch)
+		if l.peekChar() == '=' {
+			tok = l.newTwoCharToken(token.PLUS_ASSIGN)
+		} else {
+			tok = newToken(token.PLUS, l.ch)
+		}
 	case '-':
-		tok = newToken(token.MINUS, l.ch)
+		if l.peekChar() == '=' {
+			tok = l.newTwoCharToken(token.MINUS_ASSIGN)
+		} else {
+			tok = newToken(token.MINUS, l.ch)
+		}
 	case '!':
 		if l.peekChar() == '=' {
 			ch := l.ch
@@ -44,30 +103,104 @@ func (l *Lexer) NextToken() token.Token {
 			tok = newToken(token.BANG, l.ch)
 		}
 	case '/':
-		tok = newToken(token.SLASH, l.ch)
+		switch l.peekChar() {
+		case '/':
+			return l.readLineComment()
+		case '*':
+			return l.readBlockComment()
+		case '=':
+			tok = l.newTwoCharToken(token.SLASH_ASSIGN)
+		default:
+			tok = newToken(token.SLASH, l.ch)
+		}
 	case '*':
-		tok = newToken(token.ASTERISK, l.ch)
+		if l.peekChar() == '=' {
+			tok = l.newTwoCharToken(token.ASTERISK_ASSIGN)
+		} else {
+			tok = newToken(token.ASTERISK, l.ch)
+		}
+	case '%':
+		if l.peekChar() == '=' {
+			tok = l.newTwoCharToken(token.PERCENT_ASSIGN)
+		} else {
+			tok = newToken(token.PERCENT, l.ch)
+		}
 	case '<':
-		tok = newToken(token.LT, l.ch)
+		switch l.peekChar() {
+		case '=':
+			tok = l.newTwoCharToken(token.LT_EQ)
+		case '<':
+			tok = l.newTwoCharToken(token.SHIFT_LEFT)
+		default:
+			tok = newToken(token.LT, l.ch)
+		}
 	case '>':
-		tok = newToken(token.GT, l.ch)
+		switch l.peekChar() {
+		case '=':
+			tok = l.newTwoCharToken(token.GT_EQ)
+		case '>':
+			tok = l.newTwoCharToken(token.SHIFT_RIGHT)
+		default:
+			tok = newToken(token.GT, l.ch)
+		}
+	case '&':
+		if l.peekChar() == '&' {
+			tok = l.newTwoCharToken(token.AND)
+		} else {
+			tok = newToken(token.BIT_AND, l.ch)
+		}
+	case '|':
+		if l.peekChar() == '|' {
+			tok = l.newTwoCharToken(token.OR)
+		} else {
+			tok = newToken(token.BIT_OR, l.ch)
+		}
+	case '^':
+		tok = newToken(token.BIT_XOR, l.ch)
+	case '~':
+		tok = newToken(token.BIT_NOT, l.ch)
 	case ';':
 		tok = newToken(token.SEMICOLON, l.ch)
 	case ':':
 		tok = newToken(token.COLON, l.ch)
 	case ',':
 		tok = newToken(token.COMMA, l.ch)
+	case '.':
+		if strings.HasPrefix(l.input[l.position:], "...") {
+			l.readChar()
+			l.readChar()
+			tok = token.Token{Type: token.ELLIPSIS, Literal: "..."}
+		} else {
+			tok = newToken(token.ILLEGAL, l.ch)
+		}
 	case '{':
+		if len(l.templates) > 0 {
+			l.templates[len(l.templates)-1].braces += 1
+		}
 		tok = newToken(token.LBRACE, l.ch)
 	case '}':
+		if n := len(l.templates); n > 0 {
+			if l.templates[n-1].braces == 0 {
+				textBlock := l.templates[n-1].textBlock
+				l.templates = l.templates[:n-1]
+				tok = l.readStringBody(textBlock, true)
+				break
+			}
+			l.templates[n-1].braces -= 1
+		}
 		tok = newToken(token.RBRACE, l.ch)
 	case '(':
 		tok = newToken(token.LPAREN, l.ch)
 	case ')':
 		tok = newToken(token.RPAREN, l.ch)
 	case '"':
-		tok.Type = token.STRING
-		tok.Literal = l.readString()
+		if strings.HasPrefix(l.input[l.position:], `"""`) {
+			tok = l.readTextBlock()
+		} else {
+			tok = l.readString()
+		}
+	case '`':
+		tok = l.readRawString()
 	case '[':
 		tok = newToken(token.LBRACKET, l.ch)
 	case ']':
@@ -81,8 +214,7 @@ func (l *Lexer) NextToken() token.Token {
 			tok.Type = token.LookupIdent(tok.Literal)
 			return tok
 		} else if isDigit(l.ch) {
-			tok.Type = token.INT
-			tok.Literal = l.readNumber()
+			tok.Type, tok.Literal = l.readNumber()
 			return tok
 		} else {
 			tok = newToken(token.ILLEGAL, l.ch)
@@ -100,21 +232,42 @@ func (l *Lexer) skipWhitespace() {
 }
 
 func (l *Lexer) readChar() {
+	if l.ch == '\n' {
+		l.line += 1
+		l.column = 1
+	} else {
+		l.column += 1
+	}
+
 	if l.readPosition >= len(l.input) {
 		l.ch = 0
-	} else {
-		l.ch = l.input[l.readPosition]
+		l.position = l.readPosition
+		l.readPosition += 1
+		return
 	}
+
+	r, size := utf8.DecodeRuneInString(l.input[l.readPosition:])
+	l.ch = r
 	l.position = l.readPosition
-	l.readPosition += 1
+	l.readPosition += size
 }
 
-func (l *Lexer) peekChar() byte {
+func (l *Lexer) pos() token.Position {
+	return token.Position{
+		Filename: l.filename,
+		Offset:   l.position,
+		Line:     l.line,
+		Column:   l.column,
+	}
+}
+
+func (l *Lexer) peekChar() rune {
 	if l.readPosition >= len(l.input) {
 		return 0
-	} else {
-		return l.input[l.readPosition]
 	}
+
+	r, _ := utf8.DecodeRuneInString(l.input[l.readPosition:])
+	return r
 }
 
 func (l *Lexer) readIdentifier() string {
@@ -125,33 +278,252 @@ func (l *Lexer) readIdentifier() string {
 	return l.input[position:l.position]
 }
 
-func (l *Lexer) readNumber() string {
+func (l *Lexer) readNumber() (token.TokenType, string) {
 	position := l.position
-	for isDigit(l.ch) {
+	tokenType := token.TokenType(token.INT)
+
+	if l.ch == '0' && isBasePrefix(l.peekChar()) {
 		l.readChar()
+		l.readChar()
+		for isHexDigit(l.ch) || l.ch == '_' {
+			l.readChar()
+		}
+		return tokenType, l.input[position:l.position]
 	}
-	return l.input[position:l.position]
+
+	l.readDigits()
+
+	if l.ch == '.' && isDigit(l.peekChar()) {
+		tokenType = token.FLOAT
+		l.readChar()
+		l.readDigits()
+	}
+
+	if (l.ch == 'e' || l.ch == 'E') && l.peekExponent() {
+		tokenType = token.FLOAT
+		l.readChar()
+		if l.ch == '+' || l.ch == '-' {
+			l.readChar()
+		}
+		l.readDigits()
+	}
+
+	return tokenType, l.input[position:l.position]
+}
+
+func (l *Lexer) readDigits() {
+	for isDigit(l.ch) || l.ch == '_' {
+		l.readChar()
+	}
+}
+
+// peekExponent reports whether the 'e' under examination starts an exponent,
+// i.e. whether it is followed by digits with an optional sign.
+func (l *Lexer) peekExponent() bool {
+	rest := l.input[l.readPosition:]
+	if len(rest) > 0 && (rest[0] == '+' || rest[0] == '-') {
+		rest = rest[1:]
+	}
+	return len(rest) > 0 && isDigit(rune(rest[0]))
+}
+
+func (l *Lexer) atEOF() bool {
+	return l.position >= len(l.input)
 }
 
-func (l *Lexer) readString() string {
+func (l *Lexer) readLineComment() token.Token {
+	position := l.position
+	for l.ch != '\n' && !l.atEOF() {
+		l.readChar()
+	}
+	return token.Token{Type: token.COMMENT, Literal: l.input[position:l.position]}
+}
+
+func (l *Lexer) readBlockComment() token.Token {
+	position := l.position
+	l.readChar()
+	for {
+		l.readChar()
+		if l.atEOF() {
+			return token.Token{Type: token.ILLEGAL, Literal: "unterminated block comment"}
+		}
+		if l.ch == '*' && l.peekChar() == '/' {
+			l.readChar()
+			l.readChar()
+			break
+		}
+	}
+	return token.Token{Type: token.COMMENT, Literal: l.input[position:l.position]}
+}
+
+func (l *Lexer) readString() token.Token {
+	return l.readStringBody(false, false)
+}
+
+// readTextBlock reads a triple-quoted string which may span several lines. A
+// newline directly after the opening quotes is not part of the string.
+func (l *Lexer) readTextBlock() token.Token {
+	l.readChar()
+	l.readChar()
+	if l.peekChar() == '\n' {
+		l.readChar()
+	}
+
+	return l.readStringBody(true, false)
+}
+
+// readStringBody reads the characters following the current char up to the
+// closing quote(s) or the next "${". Strings containing "${" are returned as
+// a TEMPLATE_HEAD, TEMPLATE_MIDDLE and TEMPLATE_TAIL sequence with the tokens
+// of the embedded expressions in between; continued is set when reading the
+// rest of a string after the "}" closing such an expression.
+func (l *Lexer) readStringBody(textBlock, continued bool) token.Token {
+	var out strings.Builder
+	var errMsg string
+	var tokenType token.TokenType
+
+	for {
+		l.readChar()
+		if l.atEOF() {
+			return token.Token{Type: token.ILLEGAL, Literal: "unterminated string literal"}
+		}
+
+		if textBlock && strings.HasPrefix(l.input[l.position:], `"""`) {
+			l.readChar()
+			l.readChar()
+			tokenType = token.STRING
+			break
+		}
+		if !textBlock && l.ch == '"' {
+			tokenType = token.STRING
+			break
+		}
+
+		if l.ch == '$' && l.peekChar() == '{' {
+			l.readChar()
+			l.templates = append(l.templates, template{textBlock: textBlock})
+			tokenType = token.TEMPLATE_HEAD
+			break
+		}
+
+		if l.ch == '\\' {
+			if msg := l.readEscape(&out); msg != "" && errMsg == "" {
+				errMsg = msg
+			}
+			continue
+		}
+
+		out.WriteRune(l.ch)
+	}
+
+	if errMsg != "" {
+		return token.Token{Type: token.ILLEGAL, Literal: errMsg}
+	}
+
+	if continued {
+		if tokenType == token.STRING {
+			tokenType = token.TEMPLATE_TAIL
+		} else {
+			tokenType = token.TEMPLATE_MIDDLE
+		}
+	}
+
+	return token.Token{Type: tokenType, Literal: out.String()}
+}
+
+func (l *Lexer) readRawString() token.Token {
 	position := l.position + 1
 	for {
 		l.readChar()
-		if l.ch == '"' || l.ch == 0 {
+		if l.atEOF() {
+			return token.Token{Type: token.ILLEGAL, Literal: "unterminated raw string literal"}
+		}
+		if l.ch == '`' {
 			break
 		}
 	}
-	return l.input[position:l.position]
+	return token.Token{Type: token.STRING, Literal: l.input[position:l.position]}
+}
+
+// readEscape decodes the escape sequence starting at the backslash under
+// examination into out. It returns a description of the problem if the
+// sequence is invalid.
+func (l *Lexer) readEscape(out *strings.Builder) string {
+	l.readChar()
+
+	switch l.ch {
+	case 'n':
+		out.WriteByte('\n')
+	case 't':
+		out.WriteByte('\t')
+	case 'r':
+		out.WriteByte('\r')
+	case '\\', '"', '\'', '$':
+		out.WriteRune(l.ch)
+	case 'u':
+		return l.readUnicodeEscape(out, 4)
+	case 'U':
+		return l.readUnicodeEscape(out, 8)
+	default:
+		if l.atEOF() {
+			return "unterminated string literal"
+		}
+		return "invalid escape sequence \\" + string(l.ch)
+	}
+
+	return ""
+}
+
+func (l *Lexer) readUnicodeEscape(out *strings.Builder, digits int) string {
+	prefix := string(l.ch)
+
+	hex := l.input[l.readPosition:]
+	n := 0
+	for n < digits && n < len(hex) && isHexDigit(rune(hex[n])) {
+		n++
+	}
+	hex = hex[:n]
+
+	value, err := strconv.ParseUint(hex, 16, 32)
+	if n < digits || err != nil || !utf8.ValidRune(rune(value)) {
+		return "invalid unicode escape \\" + prefix + hex
+	}
+
+	for i := 0; i < digits; i++ {
+		l.readChar()
+	}
+	out.WriteRune(rune(value))
+
+	return ""
 }
 
-func isLetter(ch byte) bool {
-	return 'a' <= ch && ch <= 'z' || 'A' <= ch && ch <= 'Z' || ch == '_'
+func isLetter(ch rune) bool {
+	return 'a' <= ch && ch <= 'z' || 'A' <= ch && ch <= 'Z' || ch == '_' ||
+		ch >= utf8.RuneSelf && unicode.IsLetter(ch)
 }
 
-func isDigit(ch byte) bool {
+func isDigit(ch rune) bool {
 	return '0' <= ch && ch <= '9'
 }
 
-func newToken(tokenType token.TokenType, ch byte) token.Token {
+func isBasePrefix(ch rune) bool {
+	switch ch {
+	case 'x', 'X', 'o', 'O', 'b', 'B':
+		return true
+	}
+	return false
+}
+
+func isHexDigit(ch rune) bool {
+	return isDigit(ch) || 'a' <= ch && ch <= 'f' || 'A' <= ch && ch <= 'F'
+}
+
+func (l *Lexer) newTwoCharToken(tokenType token.TokenType) token.Token {
+	ch := l.ch
+	l.readChar()
+	return token.Token{Type: tokenType, Literal: string(ch) + string(l.ch)}
+}
+
+func newToken(tokenType token.TokenType, ch rune) token.Token {
 	return token.Token{Type: tokenType, Literal: string(ch)}
 }
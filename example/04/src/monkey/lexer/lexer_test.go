@@ -15,7 +15,7 @@ let add = fn(x, y) {
 };
 
 let result = add(five, ten);
-!-/*5;
+!-/ *5;
 5 < 10 > 5;
 
 if (5 < 10) {
@@ -141,3 +141,467 @@ if (5 < 10) {
 		}
 	}
 }
+
+func TestOperators(t *testing.T) {
+	input := `a <= b >= c && d || e % f & g | h ^ ~i << j >> k < l > m`
+
+	tests := []struct {
+		expectedType    token.TokenType
+		expectedLiteral string
+	}{
+		{token.IDENT, "a"},
+		{token.LT_EQ, "<="},
+		{token.IDENT, "b"},
+		{token.GT_EQ, ">="},
+		{token.IDENT, "c"},
+		{token.AND, "&&"},
+		{token.IDENT, "d"},
+		{token.OR, "||"},
+		{token.IDENT, "e"},
+		{token.PERCENT, "%"},
+		{token.IDENT, "f"},
+		{token.BIT_AND, "&"},
+		{token.IDENT, "g"},
+		{token.BIT_OR, "|"},
+		{token.IDENT, "h"},
+		{token.BIT_XOR, "^"},
+		{token.BIT_NOT, "~"},
+		{token.IDENT, "i"},
+		{token.SHIFT_LEFT, "<<"},
+		{token.IDENT, "j"},
+		{token.SHIFT_RIGHT, ">>"},
+		{token.IDENT, "k"},
+		{token.LT, "<"},
+		{token.IDENT, "l"},
+		{token.GT, ">"},
+		{token.IDENT, "m"},
+		{token.EOF, ""},
+	}
+
+	l := New(input)
+
+	for i, tt := range tests {
+		tok := l.NextToken()
+
+		if tok.Type != tt.expectedType {
+			t.Fatalf("tests[%d] - tokentype wrong. expected=%q, got=%q",
+				i, tt.expectedType, tok.Type)
+		}
+
+		if tok.Literal != tt.expectedLiteral {
+			t.Fatalf("tests[%d] - literal wrong. expected=%q, got=%q",
+				i, tt.expectedLiteral, tok.Literal)
+		}
+	}
+}
+
+func TestAssignmentOperators(t *testing.T) {
+	input := `a = b += c -= d *= e /= f %= g == h`
+
+	tests := []struct {
+		expectedType    token.TokenType
+		expectedLiteral string
+	}{
+		{token.IDENT, "a"},
+		{token.ASSIGN, "="},
+		{token.IDENT, "b"},
+		{token.PLUS_ASSIGN, "+="},
+		{token.IDENT, "c"},
+		{token.MINUS_ASSIGN, "-="},
+		{token.IDENT, "d"},
+		{token.ASTERISK_ASSIGN, "*="},
+		{token.IDENT, "e"},
+		{token.SLASH_ASSIGN, "/="},
+		{token.IDENT, "f"},
+		{token.PERCENT_ASSIGN, "%="},
+		{token.IDENT, "g"},
+		{token.EQ, "=="},
+		{token.IDENT, "h"},
+		{token.EOF, ""},
+	}
+
+	l := New(input)
+
+	for i, tt := range tests {
+		tok := l.NextToken()
+
+		if tok.Type != tt.expectedType {
+			t.Fatalf("tests[%d] - tokentype wrong. expected=%q, got=%q",
+				i, tt.expectedType, tok.Type)
+		}
+
+		if tok.Literal != tt.expectedLiteral {
+			t.Fatalf("tests[%d] - literal wrong. expected=%q, got=%q",
+				i, tt.expectedLiteral, tok.Literal)
+		}
+	}
+}
+
+func TestMatchTokens(t *testing.T) {
+	input := `match (x) { [a, ...rest] => a, _ => 0 } . ..`
+
+	tests := []struct {
+		expectedType    token.TokenType
+		expectedLiteral string
+	}{
+		{token.MATCH, "match"},
+		{token.LPAREN, "("},
+		{token.IDENT, "x"},
+		{token.RPAREN, ")"},
+		{token.LBRACE, "{"},
+		{token.LBRACKET, "["},
+		{token.IDENT, "a"},
+		{token.COMMA, ","},
+		{token.ELLIPSIS, "..."},
+		{token.IDENT, "rest"},
+		{token.RBRACKET, "]"},
+		{token.ARROW, "=>"},
+		{token.IDENT, "a"},
+		{token.COMMA, ","},
+		{token.IDENT, "_"},
+		{token.ARROW, "=>"},
+		{token.INT, "0"},
+		{token.RBRACE, "}"},
+		{token.ILLEGAL, "."},
+		{token.ILLEGAL, "."},
+		{token.ILLEGAL, "."},
+		{token.EOF, ""},
+	}
+
+	l := New(input)
+
+	for i, tt := range tests {
+		tok := l.NextToken()
+
+		if tok.Type != tt.expectedType {
+			t.Fatalf("tests[%d] - tokentype wrong. expected=%q, got=%q",
+				i, tt.expectedType, tok.Type)
+		}
+
+		if tok.Literal != tt.expectedLiteral {
+			t.Fatalf("tests[%d] - literal wrong. expected=%q, got=%q",
+				i, tt.expectedLiteral, tok.Literal)
+		}
+	}
+}
+
+func TestExceptionKeywords(t *testing.T) {
+	input := `try catch finally throw trying`
+
+	tests := []struct {
+		expectedType    token.TokenType
+		expectedLiteral string
+	}{
+		{token.TRY, "try"},
+		{token.CATCH, "catch"},
+		{token.FINALLY, "finally"},
+		{token.THROW, "throw"},
+		{token.IDENT, "trying"},
+		{token.EOF, ""},
+	}
+
+	l := New(input)
+
+	for i, tt := range tests {
+		tok := l.NextToken()
+
+		if tok.Type != tt.expectedType {
+			t.Fatalf("tests[%d] - tokentype wrong. expected=%q, got=%q",
+				i, tt.expectedType, tok.Type)
+		}
+
+		if tok.Literal != tt.expectedLiteral {
+			t.Fatalf("tests[%d] - literal wrong. expected=%q, got=%q",
+				i, tt.expectedLiteral, tok.Literal)
+		}
+	}
+}
+
+func TestNumbers(t *testing.T) {
+	input := `5 3.14 0.5 1e10 2.5E-3 6e+2 1.e 7.x 9e 0xFF 0o17 0b1010 1_000_000 0x_dead_BEEF 1_000.5`
+
+	tests := []struct {
+		expectedType    token.TokenType
+		expectedLiteral string
+	}{
+		{token.INT, "5"},
+		{token.FLOAT, "3.14"},
+		{token.FLOAT, "0.5"},
+		{token.FLOAT, "1e10"},
+		{token.FLOAT, "2.5E-3"},
+		{token.FLOAT, "6e+2"},
+		{token.INT, "1"},
+		{token.ILLEGAL, "."},
+		{token.IDENT, "e"},
+		{token.INT, "7"},
+		{token.ILLEGAL, "."},
+		{token.IDENT, "x"},
+		{token.INT, "9"},
+		{token.IDENT, "e"},
+		{token.INT, "0xFF"},
+		{token.INT, "0o17"},
+		{token.INT, "0b1010"},
+		{token.INT, "1_000_000"},
+		{token.INT, "0x_dead_BEEF"},
+		{token.FLOAT, "1_000.5"},
+		{token.EOF, ""},
+	}
+
+	l := New(input)
+
+	for i, tt := range tests {
+		tok := l.NextToken()
+
+		if tok.Type != tt.expectedType {
+			t.Fatalf("tests[%d] - tokentype wrong. expected=%q, got=%q",
+				i, tt.expectedType, tok.Type)
+		}
+
+		if tok.Literal != tt.expectedLiteral {
+			t.Fatalf("tests[%d] - literal wrong. expected=%q, got=%q",
+				i, tt.expectedLiteral, tok.Literal)
+		}
+	}
+}
+
+func TestStrings(t *testing.T) {
+	input := "\"tab\\there\" \"say \\\"hi\\\"\" \"a\\\\b\" \"\\u00e9\\U0001F435\" " +
+		"`raw\\n${x}` \"\"\"\nline 1\n\"quoted\"\\n\"\"\" " +
+		"\"bad \\q escape\" \"\\u12\" \"never closed"
+
+	tests := []struct {
+		expectedType    token.TokenType
+		expectedLiteral string
+	}{
+		{token.STRING, "tab\there"},
+		{token.STRING, `say "hi"`},
+		{token.STRING, `a\b`},
+		{token.STRING, "é🐵"},
+		{token.STRING, `raw\n${x}`},
+		{token.STRING, "line 1\n\"quoted\"\n"},
+		{token.ILLEGAL, `invalid escape sequence \q`},
+		{token.ILLEGAL, `invalid unicode escape \u12`},
+		{token.ILLEGAL, "unterminated string literal"},
+		{token.EOF, ""},
+	}
+
+	l := New(input)
+
+	for i, tt := range tests {
+		tok := l.NextToken()
+
+		if tok.Type != tt.expectedType {
+			t.Fatalf("tests[%d] - tokentype wrong. expected=%q, got=%q",
+				i, tt.expectedType, tok.Type)
+		}
+
+		if tok.Literal != tt.expectedLiteral {
+			t.Fatalf("tests[%d] - literal wrong. expected=%q, got=%q",
+				i, tt.expectedLiteral, tok.Literal)
+		}
+	}
+}
+
+func TestTemplateStrings(t *testing.T) {
+	input := `"Hello ${name}, you have ${len({"a": [1]}["a"])} items" ` +
+		`"${"nested ${x}"}" "cost: \${price}"`
+
+	tests := []struct {
+		expectedType    token.TokenType
+		expectedLiteral string
+	}{
+		{token.TEMPLATE_HEAD, "Hello "},
+		{token.IDENT, "name"},
+		{token.TEMPLATE_MIDDLE, ", you have "},
+		{token.IDENT, "len"},
+		{token.LPAREN, "("},
+		{token.LBRACE, "{"},
+		{token.STRING, "a"},
+		{token.COLON, ":"},
+		{token.LBRACKET, "["},
+		{token.INT, "1"},
+		{token.RBRACKET, "]"},
+		{token.RBRACE, "}"},
+		{token.LBRACKET, "["},
+		{token.STRING, "a"},
+		{token.RBRACKET, "]"},
+		{token.RPAREN, ")"},
+		{token.TEMPLATE_TAIL, " items"},
+		{token.TEMPLATE_HEAD, ""},
+		{token.TEMPLATE_HEAD, "nested "},
+		{token.IDENT, "x"},
+		{token.TEMPLATE_TAIL, ""},
+		{token.TEMPLATE_TAIL, ""},
+		{token.STRING, "cost: ${price}"},
+		{token.EOF, ""},
+	}
+
+	l := New(input)
+
+	for i, tt := range tests {
+		tok := l.NextToken()
+
+		if tok.Type != tt.expectedType {
+			t.Fatalf("tests[%d] - tokentype wrong. expected=%q, got=%q",
+				i, tt.expectedType, tok.Type)
+		}
+
+		if tok.Literal != tt.expectedLiteral {
+			t.Fatalf("tests[%d] - literal wrong. expected=%q, got=%q",
+				i, tt.expectedLiteral, tok.Literal)
+		}
+	}
+}
+
+func TestComments(t *testing.T) {
+	input := `// leading comment
+let x = 10 / 2; // trailing
+/* block
+   comment */ x`
+
+	tests := []struct {
+		expectedType    token.TokenType
+		expectedLiteral string
+	}{
+		{token.COMMENT, "// leading comment"},
+		{token.LET, "let"},
+		{token.IDENT, "x"},
+		{token.ASSIGN, "="},
+		{token.INT, "10"},
+		{token.SLASH, "/"},
+		{token.INT, "2"},
+		{token.SEMICOLON, ";"},
+		{token.COMMENT, "// trailing"},
+		{token.COMMENT, "/* block\n   comment */"},
+		{token.IDENT, "x"},
+		{token.EOF, ""},
+	}
+
+	l := New(input)
+	l.ScanComments()
+
+	for i, tt := range tests {
+		tok := l.NextToken()
+
+		if tok.Type != tt.expectedType {
+			t.Fatalf("tests[%d] - tokentype wrong. expected=%q, got=%q",
+				i, tt.expectedType, tok.Type)
+		}
+
+		if tok.Literal != tt.expectedLiteral {
+			t.Fatalf("tests[%d] - literal wrong. expected=%q, got=%q",
+				i, tt.expectedLiteral, tok.Literal)
+		}
+	}
+
+	l = New(input)
+	for {
+		tok := l.NextToken()
+		if tok.Type == token.COMMENT {
+			t.Fatalf("comment emitted without ScanComments: %q", tok.Literal)
+		}
+		if tok.Type == token.EOF {
+			break
+		}
+	}
+
+	l = New("1 /* never closed")
+	l.NextToken()
+	tok := l.NextToken()
+	if tok.Type != token.ILLEGAL || tok.Literal != "unterminated block comment" {
+		t.Errorf("wrong token for unterminated comment. got=%+v", tok)
+	}
+}
+
+func TestTokenPositions(t *testing.T) {
+	input := `let x = 5;
+  "ab" + x`
+
+	tests := []struct {
+		expectedType token.TokenType
+		expectedPos  token.Position
+		expectedEnd  token.Position
+	}{
+		{token.LET, token.Position{Offset: 0, Line: 1, Column: 1}, token.Position{Offset: 3, Line: 1, Column: 4}},
+		{token.IDENT, token.Position{Offset: 4, Line: 1, Column: 5}, token.Position{Offset: 5, Line: 1, Column: 6}},
+		{token.ASSIGN, token.Position{Offset: 6, Line: 1, Column: 7}, token.Position{Offset: 7, Line: 1, Column: 8}},
+		{token.INT, token.Position{Offset: 8, Line: 1, Column: 9}, token.Position{Offset: 9, Line: 1, Column: 10}},
+		{token.SEMICOLON, token.Position{Offset: 9, Line: 1, Column: 10}, token.Position{Offset: 10, Line: 1, Column: 11}},
+		{token.STRING, token.Position{Offset: 13, Line: 2, Column: 3}, token.Position{Offset: 17, Line: 2, Column: 7}},
+		{token.PLUS, token.Position{Offset: 18, Line: 2, Column: 8}, token.Position{Offset: 19, Line: 2, Column: 9}},
+		{token.IDENT, token.Position{Offset: 20, Line: 2, Column: 10}, token.Position{Offset: 21, Line: 2, Column: 11}},
+		{token.EOF, token.Position{Offset: 21, Line: 2, Column: 11}, token.Position{Offset: 21, Line: 2, Column: 11}},
+	}
+
+	l := New(input)
+
+	for i, tt := range tests {
+		tok := l.NextToken()
+
+		if tok.Type != tt.expectedType {
+			t.Fatalf("tests[%d] - tokentype wrong. expected=%q, got=%q",
+				i, tt.expectedType, tok.Type)
+		}
+
+		if tok.Pos != tt.expectedPos {
+			t.Errorf("tests[%d] - pos wrong. expected=%+v, got=%+v",
+				i, tt.expectedPos, tok.Pos)
+		}
+
+		if tok.End != tt.expectedEnd {
+			t.Errorf("tests[%d] - end wrong. expected=%+v, got=%+v",
+				i, tt.expectedEnd, tok.End)
+		}
+	}
+}
+
+func TestTokenFilename(t *testing.T) {
+	l := NewFile("script.monkey", "\n  foo")
+
+	tok := l.NextToken()
+	if tok.Pos.String() != "script.monkey:2:3" {
+		t.Errorf("tok.Pos.String() wrong. got=%q", tok.Pos.String())
+	}
+}
+
+func TestUnicodeInput(t *testing.T) {
+	input := `let 名前 = "🐵 モンキー";
+名前 + _über`
+
+	tests := []struct {
+		expectedType    token.TokenType
+		expectedLiteral string
+		expectedColumn  int
+	}{
+		{token.LET, "let", 1},
+		{token.IDENT, "名前", 5},
+		{token.ASSIGN, "=", 8},
+		{token.STRING, "🐵 モンキー", 10},
+		{token.SEMICOLON, ";", 18},
+		{token.IDENT, "名前", 1},
+		{token.PLUS, "+", 4},
+		{token.IDENT, "_über", 6},
+		{token.EOF, "", 11},
+	}
+
+	l := New(input)
+
+	for i, tt := range tests {
+		tok := l.NextToken()
+
+		if tok.Type != tt.expectedType {
+			t.Fatalf("tests[%d] - tokentype wrong. expected=%q, got=%q",
+				i, tt.expectedType, tok.Type)
+		}
+
+		if tok.Literal != tt.expectedLiteral {
+			t.Fatalf("tests[%d] - literal wrong. expected=%q, got=%q",
+				i, tt.expectedLiteral, tok.Literal)
+		}
+
+		if tok.Pos.Column != tt.expectedColumn {
+			t.Errorf("tests[%d] - column wrong. expected=%d, got=%d",
+				i, tt.expectedColumn, tok.Pos.Column)
+		}
+	}
+}
@@ -1,13 +1,44 @@
 package main
 
 import (
+	"flag"
 	"fmt"
+	"io/ioutil"
+	"monkey/dot"
+	"monkey/evaluator"
+	"monkey/lexer"
+	"monkey/object"
+	"monkey/parser"
 	"monkey/repl"
 	"os"
 	"os/user"
 )
 
+var (
+	checked = flag.Bool("checked", false,
+		"report integer overflow as an error instead of promoting to a big integer")
+	dotFile = flag.String("dot", "",
+		"write the syntax tree of the file as Graphviz DOT to `path` instead of running it (- for standard output)")
+	callGraphFile = flag.String("callgraph", "",
+		"write the calls made while running the file as Graphviz DOT to `path` (- for standard output)")
+)
+
 func main() {
+	flag.Parse()
+
+	switch flag.Arg(0) {
+	case "fmt":
+		os.Exit(runFmt(flag.Args()[1:]))
+	case "tokens":
+		os.Exit(runTokens(flag.Args()[1:]))
+	case "ast":
+		os.Exit(runAST(flag.Args()[1:]))
+	}
+
+	if flag.NArg() > 0 {
+		os.Exit(runFile(flag.Arg(0)))
+	}
+
 	user, err := user.Current()
 	if err != nil {
 		panic(err)
@@ -15,5 +46,78 @@ func main() {
 	fmt.Printf("Hello %s! This is the Monkey programming language!\n",
 		user.Username)
 	fmt.Printf("Feel free to type in commands\n")
-	repl.Start(os.Stdin, os.Stdout)
+	repl.Start(os.Stdin, os.Stdout, object.Options{CheckedArithmetic: *checked})
+}
+
+func runFile(filename string) int {
+	input, err := ioutil.ReadFile(filename)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		return 1
+	}
+
+	l := lexer.NewFile(filename, string(input))
+	p := parser.New(l)
+
+	program := p.ParseProgram()
+	if len(p.Errors()) != 0 {
+		for _, msg := range p.Errors() {
+			fmt.Fprintln(os.Stderr, msg)
+		}
+		return 1
+	}
+
+	if *dotFile != "" {
+		if err := writeOutput(*dotFile, dot.AST(program)); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		return 0
+	}
+
+	options := object.Options{CheckedArithmetic: *checked}
+
+	var graph *dot.CallGraph
+	if *callGraphFile != "" {
+		graph = dot.NewCallGraph()
+		options.Observer = graph
+	}
+
+	macroEnv := object.NewEnvironment()
+	*macroEnv.Options() = options
+	evaluator.DefineMacros(program, macroEnv)
+	expanded, expandErr := evaluator.ExpandMacros(program, macroEnv)
+	if expandErr != nil {
+		fmt.Fprintln(os.Stderr, expandErr.Inspect())
+		return 1
+	}
+
+	env := object.NewEnvironment()
+	*env.Options() = options
+
+	status := 0
+	evaluated := evaluator.Eval(expanded, env)
+	if evaluated != nil && evaluated.Type() == object.ERROR_OBJ {
+		fmt.Fprintln(os.Stderr, evaluated.Inspect())
+		status = 1
+	}
+
+	if graph != nil {
+		if err := writeOutput(*callGraphFile, graph.String()); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+	}
+
+	return status
+}
+
+// writeOutput writes text to the file at path, or to standard output if path
+// is "-".
+func writeOutput(path, text string) error {
+	if path == "-" {
+		_, err := os.Stdout.WriteString(text)
+		return err
+	}
+	return ioutil.WriteFile(path, []byte(text), 0644)
 }
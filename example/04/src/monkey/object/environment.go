@@ -1,5 +1,7 @@
 package object
 
+import "monkey/ast"
+
 func NewEnclosedEnvironment(outer *Environment) *Environment {
 	env := NewEnvironment()
 	env.outer = outer
@@ -11,9 +13,75 @@ func NewEnvironment() *Environment {
 	return &Environment{store: s, outer: nil}
 }
 
+// NewModuleEnvironment returns a new top-level environment for a module
+// imported by code running in importer. It shares the Imports and Options of
+// importer.
+func NewModuleEnvironment(importer *Environment) *Environment {
+	env := NewEnvironment()
+	env.imports = importer.Imports()
+	env.options = importer.Options()
+	return env
+}
+
 type Environment struct {
-	store map[string]Object
-	outer *Environment
+	store   map[string]Object
+	outer   *Environment
+	imports *Imports
+	options *Options
+}
+
+// Options control how a program is evaluated.
+type Options struct {
+	// CheckedArithmetic makes integer overflow produce an error instead of
+	// promoting the result to a BigInt.
+	CheckedArithmetic bool
+
+	// Observer, if not nil, is told about every call.
+	Observer CallObserver
+}
+
+// A CallObserver is told about every call of a function or builtin made
+// during evaluation, right before and after it runs.
+type CallObserver interface {
+	EnterCall(call *ast.CallExpression, fn Object)
+	ExitCall(call *ast.CallExpression, fn Object)
+}
+
+// Options returns the Options shared by e and every environment enclosed by
+// it, creating them in the outermost environment on first use. Set them
+// before evaluating any code in e.
+func (e *Environment) Options() *Options {
+	if e.outer != nil {
+		return e.outer.Options()
+	}
+	if e.options == nil {
+		e.options = &Options{}
+	}
+	return e.options
+}
+
+// Imports records the modules imported by a program and the modules it
+// imports in turn.
+type Imports struct {
+	// Modules holds every module imported so far by its absolute path, so
+	// that each file is evaluated at most once.
+	Modules map[string]*Module
+
+	// Importing lists the modules currently being evaluated, outermost
+	// first, to detect circular imports.
+	Importing []string
+}
+
+// Imports returns the Imports shared by e and every environment enclosed by
+// it, creating them in the outermost environment on first use.
+func (e *Environment) Imports() *Imports {
+	if e.outer != nil {
+		return e.outer.Imports()
+	}
+	if e.imports == nil {
+		e.imports = &Imports{Modules: map[string]*Module{}}
+	}
+	return e.imports
 }
 
 func (e *Environment) Get(name string) (Object, bool) {
@@ -28,3 +96,16 @@ func (e *Environment) Set(name string, val Object) Object {
 	e.store[name] = val
 	return val
 }
+
+// Assign updates an existing variable in the innermost environment that
+// defines it. It reports false if no enclosing environment defines name.
+func (e *Environment) Assign(name string, val Object) bool {
+	if _, ok := e.store[name]; ok {
+		e.store[name] = val
+		return true
+	}
+	if e.outer != nil {
+		return e.outer.Assign(name, val)
+	}
+	return false
+}
@@ -4,7 +4,11 @@ import (
 	"bytes"
 	"fmt"
 	"hash/fnv"
+	"math"
+	"math/big"
 	"monkey/ast"
+	"monkey/token"
+	"strconv"
 	"strings"
 )
 
@@ -17,16 +21,37 @@ const (
 	ERROR_OBJ = "ERROR"
 
 	INTEGER_OBJ = "INTEGER"
+	BIGINT_OBJ  = "BIGINT"
+	FLOAT_OBJ   = "FLOAT"
 	BOOLEAN_OBJ = "BOOLEAN"
 	STRING_OBJ  = "STRING"
 
 	RETURN_VALUE_OBJ = "RETURN_VALUE"
+	BREAK_OBJ        = "BREAK"
+	CONTINUE_OBJ     = "CONTINUE"
 
 	FUNCTION_OBJ = "FUNCTION"
 	BUILTIN_OBJ  = "BUILTIN"
 
 	ARRAY_OBJ = "ARRAY"
 	HASH_OBJ  = "HASH"
+
+	QUOTE_OBJ = "QUOTE"
+	MACRO_OBJ = "MACRO"
+
+	MODULE_OBJ = "MODULE"
+)
+
+// Error kinds, which let catch blocks tell runtime errors apart.
+const (
+	TYPE_ERROR       = "TypeError"
+	NAME_ERROR       = "NameError"
+	ARITHMETIC_ERROR = "ArithmeticError"
+	INDEX_ERROR      = "IndexError"
+	ARGUMENT_ERROR   = "ArgumentError"
+	MATCH_ERROR      = "MatchError"
+	THROWN_ERROR     = "ThrownError" // a value raised by throw
+	IMPORT_ERROR     = "ImportError"
 )
 
 type HashKey struct {
@@ -53,6 +78,53 @@ func (i *Integer) HashKey() HashKey {
 	return HashKey{Type: i.Type(), Value: uint64(i.Value)}
 }
 
+// BigInt holds integers that do not fit into an Integer. The evaluator only
+// produces BigInts for values outside the int64 range, so an integral value
+// always has exactly one representation.
+type BigInt struct {
+	Value *big.Int
+}
+
+func (bi *BigInt) Type() ObjectType { return BIGINT_OBJ }
+func (bi *BigInt) Inspect() string  { return bi.Value.String() }
+func (bi *BigInt) HashKey() HashKey {
+	h := fnv.New64a()
+	if bi.Value.Sign() < 0 {
+		h.Write([]byte{'-'})
+	}
+	h.Write(bi.Value.Bytes())
+
+	return HashKey{Type: bi.Type(), Value: h.Sum64()}
+}
+
+type Float struct {
+	Value float64
+}
+
+func (f *Float) Type() ObjectType { return FLOAT_OBJ }
+func (f *Float) Inspect() string {
+	s := strconv.FormatFloat(f.Value, 'g', -1, 64)
+	if !strings.ContainsAny(s, ".eIN") {
+		s += ".0"
+	}
+	return s
+}
+
+// Floats with an integral value share the hash key of the equal Integer or
+// BigInt, so that 1 and 1.0 address the same hash entry just as they compare
+// equal.
+func (f *Float) HashKey() HashKey {
+	if f.Value == math.Trunc(f.Value) && !math.IsInf(f.Value, 0) {
+		if f.Value >= math.MinInt64 && f.Value < math.MaxInt64 {
+			return (&Integer{Value: int64(f.Value)}).HashKey()
+		}
+		value, _ := big.NewFloat(f.Value).Int(nil)
+		return (&BigInt{Value: value}).HashKey()
+	}
+
+	return HashKey{Type: f.Type(), Value: math.Float64bits(f.Value)}
+}
+
 type Boolean struct {
 	Value bool
 }
@@ -83,17 +155,64 @@ type ReturnValue struct {
 func (rv *ReturnValue) Type() ObjectType { return RETURN_VALUE_OBJ }
 func (rv *ReturnValue) Inspect() string  { return rv.Value.Inspect() }
 
+// Break and Continue unwind the statements of a loop body like ReturnValue
+// unwinds a function body.
+type Break struct{}
+
+func (b *Break) Type() ObjectType { return BREAK_OBJ }
+func (b *Break) Inspect() string  { return "break" }
+
+type Continue struct{}
+
+func (c *Continue) Type() ObjectType { return CONTINUE_OBJ }
+func (c *Continue) Inspect() string  { return "continue" }
+
 type Error struct {
+	Kind    string
 	Message string
+	Value   Object         // the thrown value, for errors raised by throw
+	Pos     token.Position // where the error was raised, if known
+	Trace   []TraceFrame   // the calls it propagated out of, innermost first
+}
+
+// TraceFrame records a call to a function that an error propagated out of.
+type TraceFrame struct {
+	Function string // empty for anonymous functions
+	Pos      token.Position
+}
+
+func (f TraceFrame) String() string {
+	name := f.Function
+	if name == "" {
+		name = "<anonymous>"
+	}
+	return fmt.Sprintf("at %s (%s)", name, f.Pos)
 }
 
 func (e *Error) Type() ObjectType { return ERROR_OBJ }
-func (e *Error) Inspect() string  { return "ERROR: " + e.Message }
+func (e *Error) Inspect() string {
+	var out bytes.Buffer
+
+	out.WriteString("ERROR: ")
+	if e.Pos.IsValid() {
+		out.WriteString(e.Pos.String() + ": ")
+	}
+	out.WriteString(e.Message)
+
+	for _, frame := range e.Trace {
+		out.WriteString("\n    " + frame.String())
+	}
+
+	return out.String()
+}
 
 type Function struct {
 	Parameters []*ast.Identifier
+	Defaults   map[string]ast.Expression
+	Rest       *ast.Identifier
 	Body       *ast.BlockStatement
 	Env        *Environment
+	Name       string
 }
 
 func (f *Function) Type() ObjectType { return FUNCTION_OBJ }
@@ -102,10 +221,20 @@ func (f *Function) Inspect() string {
 
 	params := []string{}
 	for _, p := range f.Parameters {
-		params = append(params, p.String())
+		if def, ok := f.Defaults[p.Value]; ok {
+			params = append(params, p.String()+" = "+def.String())
+		} else {
+			params = append(params, p.String())
+		}
+	}
+	if f.Rest != nil {
+		params = append(params, "..."+f.Rest.String())
 	}
 
 	out.WriteString("fn")
+	if f.Name != "" {
+		out.WriteString(" " + f.Name)
+	}
 	out.WriteString("(")
 	out.WriteString(strings.Join(params, ", "))
 	out.WriteString(") {\n")
@@ -115,6 +244,50 @@ func (f *Function) Inspect() string {
 	return out.String()
 }
 
+// Module is the result of importing a file: the values of the names it
+// exports, keyed by name.
+type Module struct {
+	Path    string
+	Exports map[string]Object
+}
+
+func (m *Module) Type() ObjectType { return MODULE_OBJ }
+func (m *Module) Inspect() string  { return "module(" + strconv.Quote(m.Path) + ")" }
+
+type Quote struct {
+	Node ast.Node
+}
+
+func (q *Quote) Type() ObjectType { return QUOTE_OBJ }
+func (q *Quote) Inspect() string {
+	return "QUOTE(" + q.Node.String() + ")"
+}
+
+type Macro struct {
+	Parameters []*ast.Identifier
+	Body       *ast.BlockStatement
+	Env        *Environment
+}
+
+func (m *Macro) Type() ObjectType { return MACRO_OBJ }
+func (m *Macro) Inspect() string {
+	var out bytes.Buffer
+
+	params := []string{}
+	for _, p := range m.Parameters {
+		params = append(params, p.String())
+	}
+
+	out.WriteString("macro")
+	out.WriteString("(")
+	out.WriteString(strings.Join(params, ", "))
+	out.WriteString(") {\n")
+	out.WriteString(m.Body.String())
+	out.WriteString("\n}")
+
+	return out.String()
+}
+
 type String struct {
 	Value string
 }
@@ -160,8 +333,32 @@ type HashPair struct {
 	Value Object
 }
 
+// Hash keeps its pairs in insertion order: Keys holds the keys of Pairs in
+// the order they were first set. Use Set to add pairs.
 type Hash struct {
 	Pairs map[HashKey]HashPair
+	Keys  []HashKey
+}
+
+// Set stores pair under key. A new key goes after all existing ones, while
+// setting an existing key keeps its place.
+func (h *Hash) Set(key HashKey, pair HashPair) {
+	if h.Pairs == nil {
+		h.Pairs = make(map[HashKey]HashPair)
+	}
+	if _, ok := h.Pairs[key]; !ok {
+		h.Keys = append(h.Keys, key)
+	}
+	h.Pairs[key] = pair
+}
+
+// OrderedPairs returns the pairs of h in insertion order.
+func (h *Hash) OrderedPairs() []HashPair {
+	pairs := make([]HashPair, 0, len(h.Keys))
+	for _, key := range h.Keys {
+		pairs = append(pairs, h.Pairs[key])
+	}
+	return pairs
 }
 
 func (h *Hash) Type() ObjectType { return HASH_OBJ }
@@ -169,7 +366,7 @@ func (h *Hash) Inspect() string {
 	var out bytes.Buffer
 
 	pairs := []string{}
-	for _, pair := range h.Pairs {
+	for _, pair := range h.OrderedPairs() {
 		pairs = append(pairs, fmt.Sprintf("%s: %s",
 			pair.Key.Inspect(), pair.Value.Inspect()))
 	}
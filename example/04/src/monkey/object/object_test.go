@@ -1,6 +1,11 @@
 package object
 
-import "testing"
+import (
+	"math"
+	"math/big"
+	"strconv"
+	"testing"
+)
 
 func TestStringHashKey(t *testing.T) {
 	hello1 := &String{Value: "Hello World"}
@@ -40,6 +45,49 @@ func TestBooleanHashKey(t *testing.T) {
 	}
 }
 
+func TestFloatHashKey(t *testing.T) {
+	half1 := &Float{Value: 0.5}
+	half2 := &Float{Value: 0.5}
+	quarter := &Float{Value: 0.25}
+
+	if half1.HashKey() != half2.HashKey() {
+		t.Errorf("floats with same content have different hash keys")
+	}
+
+	if half1.HashKey() == quarter.HashKey() {
+		t.Errorf("floats with different content have same hash keys")
+	}
+
+	if (&Float{Value: 2}).HashKey() != (&Integer{Value: 2}).HashKey() {
+		t.Errorf("integral float has different hash key than equal integer")
+	}
+
+	for _, f := range []float64{1e20, -1e20, 9223372036854775808} {
+		value, _ := new(big.Int).SetString(strconv.FormatFloat(f, 'f', 0, 64), 10)
+		if (&Float{Value: f}).HashKey() != (&BigInt{Value: value}).HashKey() {
+			t.Errorf("integral float %g has different hash key than equal big integer", f)
+		}
+	}
+
+	if (&Float{Value: math.Inf(1)}).HashKey() == (&Float{Value: math.Inf(-1)}).HashKey() {
+		t.Errorf("infinities have same hash keys")
+	}
+}
+
+func TestBigIntHashKey(t *testing.T) {
+	big1 := &BigInt{Value: new(big.Int).Lsh(big.NewInt(1), 70)}
+	big2 := &BigInt{Value: new(big.Int).Lsh(big.NewInt(1), 70)}
+	negative := &BigInt{Value: new(big.Int).Neg(big1.Value)}
+
+	if big1.HashKey() != big2.HashKey() {
+		t.Errorf("big integers with same content have different hash keys")
+	}
+
+	if big1.HashKey() == negative.HashKey() {
+		t.Errorf("big integers with different sign have same hash keys")
+	}
+}
+
 func TestIntegerHashKey(t *testing.T) {
 	one1 := &Integer{Value: 1}
 	one2 := &Integer{Value: 1}
@@ -58,3 +106,40 @@ func TestIntegerHashKey(t *testing.T) {
 		t.Errorf("integers with twoerent content have same hash keys")
 	}
 }
+
+func TestFloatInspect(t *testing.T) {
+	tests := []struct {
+		value    float64
+		expected string
+	}{
+		{2, "2.0"},
+		{2.5, "2.5"},
+		{-0.125, "-0.125"},
+		{1e21, "1e+21"},
+	}
+
+	for _, tt := range tests {
+		f := &Float{Value: tt.value}
+		if f.Inspect() != tt.expected {
+			t.Errorf("Inspect() wrong. expected=%q, got=%q",
+				tt.expected, f.Inspect())
+		}
+	}
+}
+
+func TestHashSetKeepsInsertionOrder(t *testing.T) {
+	hash := &Hash{}
+	for i, key := range []string{"c", "a", "b", "a"} {
+		k := &String{Value: key}
+		hash.Set(k.HashKey(), HashPair{Key: k, Value: &Integer{Value: int64(i)}})
+	}
+
+	if len(hash.Keys) != 3 {
+		t.Fatalf("hash has wrong number of keys. got=%d", len(hash.Keys))
+	}
+
+	expected := "{c: 0, a: 3, b: 2}"
+	if hash.Inspect() != expected {
+		t.Errorf("wrong Inspect. expected=%q, got=%q", expected, hash.Inspect())
+	}
+}
@@ -0,0 +1,119 @@
+package parser
+
+import (
+	"fmt"
+	"monkey/token"
+)
+
+// MaxErrors is the number of errors after which the parser gives up.
+const MaxErrors = 10
+
+type ErrorCode string
+
+const (
+	UnexpectedToken   ErrorCode = "unexpected-token"
+	MissingExpression ErrorCode = "missing-expression"
+	InvalidLiteral    ErrorCode = "invalid-literal"
+	InvalidAssignment ErrorCode = "invalid-assignment"
+	InvalidPattern    ErrorCode = "invalid-pattern"
+	InvalidParameter  ErrorCode = "invalid-parameter"
+	IllegalToken      ErrorCode = "illegal-token"
+	MisplacedKeyword  ErrorCode = "misplaced-keyword"
+	TooManyErrors     ErrorCode = "too-many-errors"
+)
+
+type ParseError struct {
+	Code     ErrorCode
+	Pos      token.Position
+	Expected []token.TokenType // the token types that would have been valid, if known
+	Found    token.Token
+	Message  string
+}
+
+func (e *ParseError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Pos, e.Message)
+}
+
+func (p *Parser) Errors() []*ParseError {
+	return p.errors
+}
+
+// addError records an error unless the parser is still recovering from a
+// previous one in the same statement, so that every mistake is only reported
+// once.
+func (p *Parser) addError(
+	code ErrorCode,
+	found token.Token,
+	expected []token.TokenType,
+	format string,
+	a ...interface{},
+) {
+	if p.recovering || p.gaveUp {
+		return
+	}
+	p.recovering = true
+
+	if len(p.errors) == MaxErrors {
+		p.errors = append(p.errors, &ParseError{
+			Code:    TooManyErrors,
+			Pos:     found.Pos,
+			Found:   found,
+			Message: "too many errors",
+		})
+		p.gaveUp = true
+		return
+	}
+
+	p.errors = append(p.errors, &ParseError{
+		Code:     code,
+		Pos:      found.Pos,
+		Expected: expected,
+		Found:    found,
+		Message:  fmt.Sprintf(format, a...),
+	})
+}
+
+func (p *Parser) peekError(t token.TokenType) {
+	p.addError(UnexpectedToken, p.peekToken, []token.TokenType{t},
+		"expected next token to be %s, got %s instead", t, p.peekToken.Type)
+}
+
+func (p *Parser) noPrefixParseFnError(t token.TokenType) {
+	p.addError(MissingExpression, p.curToken, nil,
+		"no prefix parse function for %s found", t)
+}
+
+// synchronize skips the rest of a statement that contained an error. It stops
+// on the statement's semicolon or before the next token that can only start a
+// new statement or close the enclosing block, skipping over nested blocks and
+// hash literals. depth is the braceDepth at the start of the statement, so
+// that braces opened before the error was found are skipped too.
+func (p *Parser) synchronize(depth int) {
+	p.recovering = false
+
+	for !p.curTokenIs(token.EOF) {
+		// the number of braces opened in the statement, including curToken
+		nested := p.braceDepth - depth
+		switch p.curToken.Type {
+		case token.LBRACE:
+			nested++
+		case token.RBRACE:
+			nested--
+		case token.SEMICOLON:
+			if nested <= 0 {
+				return
+			}
+		}
+
+		if nested <= 0 {
+			switch p.peekToken.Type {
+			case token.LET, token.RETURN, token.WHILE, token.FOR,
+				token.BREAK, token.CONTINUE, token.THROW, token.EXPORT,
+				token.RBRACE, token.EOF:
+				return
+			}
+		}
+
+		p.nextToken()
+	}
+}
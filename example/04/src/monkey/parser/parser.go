@@ -1,7 +1,8 @@
 package parser
 
 import (
-	"fmt"
+	"errors"
+	"math/big"
 	"monkey/ast"
 	"monkey/lexer"
 	"monkey/token"
@@ -11,26 +12,45 @@ import (
 const (
 	_ int = iota
 	LOWEST
+	ASSIGNMENT  // = or +=
+	LOGICAL_OR  // ||
+	LOGICAL_AND // &&
 	EQUALS      // ==
 	LESSGREATER // > or <
-	SUM         // +
-	PRODUCT     // *
+	SUM         // + or |
+	PRODUCT     // * or &
 	PREFIX      // -X or !X
 	CALL        // myFunction(X)
 	INDEX       // array[index]
 )
 
 var precedences = map[token.TokenType]int{
-	token.EQ:       EQUALS,
-	token.NOT_EQ:   EQUALS,
-	token.LT:       LESSGREATER,
-	token.GT:       LESSGREATER,
-	token.PLUS:     SUM,
-	token.MINUS:    SUM,
-	token.SLASH:    PRODUCT,
-	token.ASTERISK: PRODUCT,
-	token.LPAREN:   CALL,
-	token.LBRACKET: INDEX,
+	token.ASSIGN:          ASSIGNMENT,
+	token.PLUS_ASSIGN:     ASSIGNMENT,
+	token.MINUS_ASSIGN:    ASSIGNMENT,
+	token.ASTERISK_ASSIGN: ASSIGNMENT,
+	token.SLASH_ASSIGN:    ASSIGNMENT,
+	token.PERCENT_ASSIGN:  ASSIGNMENT,
+	token.OR:              LOGICAL_OR,
+	token.AND:             LOGICAL_AND,
+	token.EQ:              EQUALS,
+	token.NOT_EQ:          EQUALS,
+	token.LT:              LESSGREATER,
+	token.GT:              LESSGREATER,
+	token.LT_EQ:           LESSGREATER,
+	token.GT_EQ:           LESSGREATER,
+	token.PLUS:            SUM,
+	token.MINUS:           SUM,
+	token.BIT_OR:          SUM,
+	token.BIT_XOR:         SUM,
+	token.SLASH:           PRODUCT,
+	token.ASTERISK:        PRODUCT,
+	token.PERCENT:         PRODUCT,
+	token.BIT_AND:         PRODUCT,
+	token.SHIFT_LEFT:      PRODUCT,
+	token.SHIFT_RIGHT:     PRODUCT,
+	token.LPAREN:          CALL,
+	token.LBRACKET:        INDEX,
 }
 
 type (
@@ -39,8 +59,16 @@ type (
 )
 
 type Parser struct {
-	l      *lexer.Lexer
-	errors []string
+	l        *lexer.Lexer
+	errors   []*ParseError
+	comments []*ast.Comment
+
+	recovering bool // an error was reported in the current statement
+	gaveUp     bool // MaxErrors was reached
+
+	loopDepth  int // number of loops enclosing the current statement
+	blockDepth int // number of blocks enclosing the current statement
+	braceDepth int // number of { before curToken that are not closed yet
 
 	curToken  token.Token
 	peekToken token.Token
@@ -52,19 +80,27 @@ type Parser struct {
 func New(l *lexer.Lexer) *Parser {
 	p := &Parser{
 		l:      l,
-		errors: []string{},
+		errors: []*ParseError{},
 	}
 
 	p.prefixParseFns = make(map[token.TokenType]prefixParseFn)
+	p.registerPrefix(token.ILLEGAL, p.parseIllegal)
 	p.registerPrefix(token.IDENT, p.parseIdentifier)
 	p.registerPrefix(token.INT, p.parseIntegerLiteral)
+	p.registerPrefix(token.FLOAT, p.parseFloatLiteral)
 	p.registerPrefix(token.STRING, p.parseStringLiteral)
+	p.registerPrefix(token.TEMPLATE_HEAD, p.parseTemplateLiteral)
 	p.registerPrefix(token.BANG, p.parsePrefixExpression)
 	p.registerPrefix(token.MINUS, p.parsePrefixExpression)
+	p.registerPrefix(token.BIT_NOT, p.parsePrefixExpression)
 	p.registerPrefix(token.TRUE, p.parseBoolean)
 	p.registerPrefix(token.FALSE, p.parseBoolean)
 	p.registerPrefix(token.LPAREN, p.parseGroupedExpression)
 	p.registerPrefix(token.IF, p.parseIfExpression)
+	p.registerPrefix(token.MATCH, p.parseMatchExpression)
+	p.registerPrefix(token.TRY, p.parseTryExpression)
+	p.registerPrefix(token.MACRO, p.parseMacroLiteral)
+	p.registerPrefix(token.IMPORT, p.parseImportExpression)
 	p.registerPrefix(token.FUNCTION, p.parseFunctionLiteral)
 	p.registerPrefix(token.LBRACKET, p.parseArrayLiteral)
 	p.registerPrefix(token.LBRACE, p.parseHashLiteral)
@@ -78,6 +114,23 @@ func New(l *lexer.Lexer) *Parser {
 	p.registerInfix(token.NOT_EQ, p.parseInfixExpression)
 	p.registerInfix(token.LT, p.parseInfixExpression)
 	p.registerInfix(token.GT, p.parseInfixExpression)
+	p.registerInfix(token.LT_EQ, p.parseInfixExpression)
+	p.registerInfix(token.GT_EQ, p.parseInfixExpression)
+	p.registerInfix(token.PERCENT, p.parseInfixExpression)
+	p.registerInfix(token.AND, p.parseInfixExpression)
+	p.registerInfix(token.OR, p.parseInfixExpression)
+	p.registerInfix(token.BIT_AND, p.parseInfixExpression)
+	p.registerInfix(token.BIT_OR, p.parseInfixExpression)
+	p.registerInfix(token.BIT_XOR, p.parseInfixExpression)
+	p.registerInfix(token.SHIFT_LEFT, p.parseInfixExpression)
+	p.registerInfix(token.SHIFT_RIGHT, p.parseInfixExpression)
+
+	p.registerInfix(token.ASSIGN, p.parseAssignExpression)
+	p.registerInfix(token.PLUS_ASSIGN, p.parseAssignExpression)
+	p.registerInfix(token.MINUS_ASSIGN, p.parseAssignExpression)
+	p.registerInfix(token.ASTERISK_ASSIGN, p.parseAssignExpression)
+	p.registerInfix(token.SLASH_ASSIGN, p.parseAssignExpression)
+	p.registerInfix(token.PERCENT_ASSIGN, p.parseAssignExpression)
 
 	p.registerInfix(token.LPAREN, p.parseCallExpression)
 	p.registerInfix(token.LBRACKET, p.parseIndexExpression)
@@ -90,8 +143,20 @@ func New(l *lexer.Lexer) *Parser {
 }
 
 func (p *Parser) nextToken() {
+	switch p.curToken.Type {
+	case token.LBRACE:
+		p.braceDepth++
+	case token.RBRACE:
+		p.braceDepth--
+	}
+
 	p.curToken = p.peekToken
 	p.peekToken = p.l.NextToken()
+
+	for p.peekTokenIs(token.COMMENT) {
+		p.comments = append(p.comments, &ast.Comment{Token: p.peekToken})
+		p.peekToken = p.l.NextToken()
+	}
 }
 
 func (p *Parser) curTokenIs(t token.TokenType) bool {
@@ -112,33 +177,24 @@ func (p *Parser) expectPeek(t token.TokenType) bool {
 	}
 }
 
-func (p *Parser) Errors() []string {
-	return p.errors
-}
-
-func (p *Parser) peekError(t token.TokenType) {
-	msg := fmt.Sprintf("expected next token to be %s, got %s instead",
-		t, p.peekToken.Type)
-	p.errors = append(p.errors, msg)
-}
-
-func (p *Parser) noPrefixParseFnError(t token.TokenType) {
-	msg := fmt.Sprintf("no prefix parse function for %s found", t)
-	p.errors = append(p.errors, msg)
-}
-
 func (p *Parser) ParseProgram() *ast.Program {
 	program := &ast.Program{}
 	program.Statements = []ast.Statement{}
 
-	for !p.curTokenIs(token.EOF) {
+	for !p.curTokenIs(token.EOF) && !p.gaveUp {
+		depth := p.braceDepth
 		stmt := p.parseStatement()
 		if stmt != nil {
 			program.Statements = append(program.Statements, stmt)
 		}
+		if p.recovering {
+			p.synchronize(depth)
+		}
 		p.nextToken()
 	}
 
+	program.Comments = p.comments
+
 	return program
 }
 
@@ -148,6 +204,21 @@ func (p *Parser) parseStatement() ast.Statement {
 		return p.parseLetStatement()
 	case token.RETURN:
 		return p.parseReturnStatement()
+	case token.WHILE:
+		return p.parseWhileStatement()
+	case token.FOR:
+		return p.parseForStatement()
+	case token.BREAK, token.CONTINUE:
+		return p.parseLoopControlStatement()
+	case token.THROW:
+		return p.parseThrowStatement()
+	case token.EXPORT:
+		return p.parseExportStatement()
+	case token.FUNCTION:
+		if p.peekTokenIs(token.IDENT) {
+			return p.parseFunctionStatement()
+		}
+		return p.parseExpressionStatement()
 	default:
 		return p.parseExpressionStatement()
 	}
@@ -156,16 +227,92 @@ func (p *Parser) parseStatement() ast.Statement {
 func (p *Parser) parseLetStatement() *ast.LetStatement {
 	stmt := &ast.LetStatement{Token: p.curToken}
 
-	if !p.expectPeek(token.IDENT) {
+	if p.peekTokenIs(token.LBRACKET) || p.peekTokenIs(token.LBRACE) {
+		p.nextToken()
+		if stmt.Pattern = p.parsePattern(); stmt.Pattern == nil {
+			return nil
+		}
+	} else {
+		if !p.expectPeek(token.IDENT) {
+			return nil
+		}
+
+		stmt.Name = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+	}
+
+	if !p.expectPeek(token.ASSIGN) {
 		return nil
 	}
 
+	p.nextToken()
+
+	stmt.Value = p.parseExpression(LOWEST)
+
+	if fl, ok := stmt.Value.(*ast.FunctionLiteral); ok && stmt.Name != nil {
+		fl.Name = stmt.Name.Value
+	}
+
+	if p.peekTokenIs(token.SEMICOLON) {
+		p.nextToken()
+	}
+
+	return stmt
+}
+
+func (p *Parser) parseFunctionStatement() *ast.FunctionStatement {
+	stmt := &ast.FunctionStatement{Token: p.curToken}
+
+	p.nextToken()
 	stmt.Name = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
 
-	if !p.expectPeek(token.ASSIGN) {
+	stmt.Function = &ast.FunctionLiteral{Token: stmt.Token, Name: stmt.Name.Value}
+	if !p.parseFunction(stmt.Function) {
 		return nil
 	}
 
+	if p.peekTokenIs(token.SEMICOLON) {
+		p.nextToken()
+	}
+
+	return stmt
+}
+
+func (p *Parser) parseExportStatement() *ast.ExportStatement {
+	stmt := &ast.ExportStatement{Token: p.curToken}
+
+	if p.blockDepth > 0 {
+		p.addError(MisplacedKeyword, p.curToken, nil,
+			"export is only allowed at the top level")
+		return nil
+	}
+
+	p.nextToken()
+
+	switch {
+	case p.curTokenIs(token.LET):
+		if let := p.parseLetStatement(); let != nil {
+			stmt.Statement = let
+		}
+	case p.curTokenIs(token.FUNCTION) && p.peekTokenIs(token.IDENT):
+		if fn := p.parseFunctionStatement(); fn != nil {
+			stmt.Statement = fn
+		}
+	default:
+		p.addError(UnexpectedToken, p.curToken,
+			[]token.TokenType{token.LET, token.FUNCTION},
+			"expected let or fn declaration after export, got %s instead", p.curToken.Type)
+	}
+
+	if stmt.Statement == nil {
+		return nil
+	}
+
+	return stmt
+}
+
+func (p *Parser) parseThrowStatement() *ast.ThrowStatement {
+	stmt := &ast.ThrowStatement{Token: p.curToken}
+
 	p.nextToken()
 
 	stmt.Value = p.parseExpression(LOWEST)
@@ -191,6 +338,97 @@ func (p *Parser) parseReturnStatement() *ast.ReturnStatement {
 	return stmt
 }
 
+func (p *Parser) parseWhileStatement() ast.Statement {
+	stmt := &ast.WhileStatement{Token: p.curToken}
+
+	if !p.expectPeek(token.LPAREN) {
+		return nil
+	}
+
+	p.nextToken()
+	stmt.Condition = p.parseExpression(LOWEST)
+
+	if !p.expectPeek(token.RPAREN) {
+		return nil
+	}
+
+	if !p.expectPeek(token.LBRACE) {
+		return nil
+	}
+
+	stmt.Body = p.parseLoopBody()
+
+	if p.peekTokenIs(token.SEMICOLON) {
+		p.nextToken()
+	}
+
+	return stmt
+}
+
+func (p *Parser) parseForStatement() ast.Statement {
+	stmt := &ast.ForStatement{Token: p.curToken}
+
+	if !p.expectPeek(token.LPAREN) {
+		return nil
+	}
+
+	if !p.expectPeek(token.IDENT) {
+		return nil
+	}
+	stmt.Variable = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+
+	if !p.expectPeek(token.IN) {
+		return nil
+	}
+
+	p.nextToken()
+	stmt.Iterable = p.parseExpression(LOWEST)
+
+	if !p.expectPeek(token.RPAREN) {
+		return nil
+	}
+
+	if !p.expectPeek(token.LBRACE) {
+		return nil
+	}
+
+	stmt.Body = p.parseLoopBody()
+
+	if p.peekTokenIs(token.SEMICOLON) {
+		p.nextToken()
+	}
+
+	return stmt
+}
+
+func (p *Parser) parseLoopBody() *ast.BlockStatement {
+	p.loopDepth++
+	defer func() { p.loopDepth-- }()
+
+	return p.parseBlockStatement()
+}
+
+func (p *Parser) parseLoopControlStatement() ast.Statement {
+	var stmt ast.Statement
+	if p.curTokenIs(token.BREAK) {
+		stmt = &ast.BreakStatement{Token: p.curToken}
+	} else {
+		stmt = &ast.ContinueStatement{Token: p.curToken}
+	}
+
+	if p.loopDepth == 0 {
+		p.addError(MisplacedKeyword, p.curToken, nil,
+			"%s outside of a loop", p.curToken.Literal)
+		return nil
+	}
+
+	if p.peekTokenIs(token.SEMICOLON) {
+		p.nextToken()
+	}
+
+	return stmt
+}
+
 func (p *Parser) parseExpressionStatement() *ast.ExpressionStatement {
 	stmt := &ast.ExpressionStatement{Token: p.curToken}
 
@@ -225,20 +463,28 @@ func (p *Parser) parseExpression(precedence int) ast.Expression {
 	return leftExp
 }
 
-func (p *Parser) peekPrecedence() int {
-	if p, ok := precedences[p.peekToken.Type]; ok {
+// Precedence returns how tightly the infix operator t binds its operands, or
+// LOWEST if t is not an infix operator.
+func Precedence(t token.TokenType) int {
+	if p, ok := precedences[t]; ok {
 		return p
 	}
 
 	return LOWEST
 }
 
+func (p *Parser) peekPrecedence() int {
+	return Precedence(p.peekToken.Type)
+}
+
 func (p *Parser) curPrecedence() int {
-	if p, ok := precedences[p.curToken.Type]; ok {
-		return p
-	}
+	return Precedence(p.curToken.Type)
+}
 
-	return LOWEST
+func (p *Parser) parseIllegal() ast.Expression {
+	p.addError(IllegalToken, p.curToken, nil,
+		"illegal token: %s", p.curToken.Literal)
+	return nil
 }
 
 func (p *Parser) parseIdentifier() ast.Expression {
@@ -248,10 +494,43 @@ func (p *Parser) parseIdentifier() ast.Expression {
 func (p *Parser) parseIntegerLiteral() ast.Expression {
 	lit := &ast.IntegerLiteral{Token: p.curToken}
 
+	if hasLeadingZero(p.curToken.Literal) {
+		p.addError(InvalidLiteral, p.curToken, nil,
+			"leading zeros are not allowed in %q; use 0o for octal", p.curToken.Literal)
+		return nil
+	}
+
 	value, err := strconv.ParseInt(p.curToken.Literal, 0, 64)
+	if errors.Is(err, strconv.ErrRange) {
+		if value, ok := new(big.Int).SetString(p.curToken.Literal, 0); ok {
+			lit.Big = value
+			return lit
+		}
+	}
+	if err != nil {
+		p.addError(InvalidLiteral, p.curToken, nil,
+			"could not parse %q as integer", p.curToken.Literal)
+		return nil
+	}
+
+	lit.Value = value
+
+	return lit
+}
+
+// hasLeadingZero reports whether lit is a decimal integer literal starting
+// with 0, which strconv.ParseInt would read as octal.
+func hasLeadingZero(lit string) bool {
+	return len(lit) > 1 && lit[0] == '0' && ('0' <= lit[1] && lit[1] <= '9' || lit[1] == '_')
+}
+
+func (p *Parser) parseFloatLiteral() ast.Expression {
+	lit := &ast.FloatLiteral{Token: p.curToken}
+
+	value, err := strconv.ParseFloat(p.curToken.Literal, 64)
 	if err != nil {
-		msg := fmt.Sprintf("could not parse %q as integer", p.curToken.Literal)
-		p.errors = append(p.errors, msg)
+		p.addError(InvalidLiteral, p.curToken, nil,
+			"could not parse %q as float", p.curToken.Literal)
 		return nil
 	}
 
@@ -264,6 +543,29 @@ func (p *Parser) parseStringLiteral() ast.Expression {
 	return &ast.StringLiteral{Token: p.curToken, Value: p.curToken.Literal}
 }
 
+func (p *Parser) parseTemplateLiteral() ast.Expression {
+	template := &ast.TemplateLiteral{Token: p.curToken}
+	template.Parts = append(template.Parts, p.parseStringLiteral())
+
+	for {
+		p.nextToken()
+		template.Parts = append(template.Parts, p.parseExpression(LOWEST))
+
+		if p.peekTokenIs(token.TEMPLATE_MIDDLE) {
+			p.nextToken()
+			template.Parts = append(template.Parts, p.parseStringLiteral())
+			continue
+		}
+
+		if !p.expectPeek(token.TEMPLATE_TAIL) {
+			return nil
+		}
+		template.Parts = append(template.Parts, p.parseStringLiteral())
+
+		return template
+	}
+}
+
 func (p *Parser) parsePrefixExpression() ast.Expression {
 	expression := &ast.PrefixExpression{
 		Token:    p.curToken,
@@ -291,6 +593,30 @@ func (p *Parser) parseInfixExpression(left ast.Expression) ast.Expression {
 	return expression
 }
 
+func (p *Parser) parseAssignExpression(target ast.Expression) ast.Expression {
+	expression := &ast.AssignExpression{
+		Token:    p.curToken,
+		Operator: p.curToken.Literal,
+		Target:   target,
+	}
+
+	switch target.(type) {
+	case nil:
+		return nil
+	case *ast.Identifier, *ast.IndexExpression:
+	default:
+		p.addError(InvalidAssignment, p.curToken, nil,
+			"invalid target on the left of %s", p.curToken.Literal)
+		return nil
+	}
+
+	p.nextToken()
+	// assignments are right-associative: a = b = c is a = (b = c)
+	expression.Value = p.parseExpression(ASSIGNMENT - 1)
+
+	return expression
+}
+
 func (p *Parser) parseBoolean() ast.Expression {
 	return &ast.Boolean{Token: p.curToken, Value: p.curTokenIs(token.TRUE)}
 }
@@ -330,6 +656,15 @@ func (p *Parser) parseIfExpression() ast.Expression {
 	if p.peekTokenIs(token.ELSE) {
 		p.nextToken()
 
+		if p.peekTokenIs(token.IF) {
+			p.nextToken()
+			expression.Alternative = p.parseElseIf()
+			if expression.Alternative == nil {
+				return nil
+			}
+			return expression
+		}
+
 		if !p.expectPeek(token.LBRACE) {
 			return nil
 		}
@@ -340,74 +675,487 @@ func (p *Parser) parseIfExpression() ast.Expression {
 	return expression
 }
 
+// parseElseIf parses the if expression following an else as the only
+// statement of the alternative block.
+func (p *Parser) parseElseIf() *ast.BlockStatement {
+	tok := p.curToken
+
+	alternative := p.parseIfExpression()
+	if alternative == nil {
+		return nil
+	}
+
+	return &ast.BlockStatement{
+		Token: tok,
+		Statements: []ast.Statement{
+			&ast.ExpressionStatement{Token: tok, Expression: alternative},
+		},
+	}
+}
+
+func (p *Parser) parseTryExpression() ast.Expression {
+	expression := &ast.TryExpression{Token: p.curToken}
+
+	if !p.expectPeek(token.LBRACE) {
+		return nil
+	}
+
+	expression.Block = p.parseBlockStatement()
+
+	if p.peekTokenIs(token.CATCH) {
+		p.nextToken()
+
+		if p.peekTokenIs(token.LPAREN) {
+			p.nextToken()
+
+			if !p.expectPeek(token.IDENT) {
+				return nil
+			}
+			expression.Param = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+
+			if !p.expectPeek(token.RPAREN) {
+				return nil
+			}
+		}
+
+		if !p.expectPeek(token.LBRACE) {
+			return nil
+		}
+
+		expression.Catch = p.parseBlockStatement()
+	}
+
+	if p.peekTokenIs(token.FINALLY) {
+		p.nextToken()
+
+		if !p.expectPeek(token.LBRACE) {
+			return nil
+		}
+
+		expression.Finally = p.parseBlockStatement()
+	}
+
+	if expression.Catch == nil && expression.Finally == nil {
+		p.addError(UnexpectedToken, p.peekToken,
+			[]token.TokenType{token.CATCH, token.FINALLY},
+			"expected catch or finally after try block, got %s instead", p.peekToken.Type)
+		return nil
+	}
+
+	return expression
+}
+
+func (p *Parser) parseMatchExpression() ast.Expression {
+	expression := &ast.MatchExpression{Token: p.curToken}
+
+	if !p.expectPeek(token.LPAREN) {
+		return nil
+	}
+
+	p.nextToken()
+	expression.Subject = p.parseExpression(LOWEST)
+
+	if !p.expectPeek(token.RPAREN) {
+		return nil
+	}
+
+	if !p.expectPeek(token.LBRACE) {
+		return nil
+	}
+
+	for !p.peekTokenIs(token.RBRACE) {
+		p.nextToken()
+
+		arm := p.parseMatchArm()
+		if arm == nil {
+			return nil
+		}
+		expression.Arms = append(expression.Arms, arm)
+
+		// the comma after an arm with a block body is optional
+		if p.peekTokenIs(token.COMMA) {
+			p.nextToken()
+		} else if !p.peekTokenIs(token.RBRACE) && !p.curTokenIs(token.RBRACE) {
+			p.peekError(token.COMMA)
+			return nil
+		}
+	}
+
+	if !p.expectPeek(token.RBRACE) {
+		return nil
+	}
+	expression.Rbrace = p.curToken
+
+	return expression
+}
+
+func (p *Parser) parseMatchArm() *ast.MatchArm {
+	arm := &ast.MatchArm{Pattern: p.parsePattern()}
+	if arm.Pattern == nil {
+		return nil
+	}
+
+	if !p.expectPeek(token.ARROW) {
+		return nil
+	}
+
+	p.nextToken()
+
+	if p.curTokenIs(token.LBRACE) {
+		arm.Body = p.parseBlockStatement()
+		return arm
+	}
+
+	tok := p.curToken
+	value := p.parseExpression(LOWEST)
+	if value == nil {
+		return nil
+	}
+
+	arm.Body = &ast.BlockStatement{
+		Token: tok,
+		Statements: []ast.Statement{
+			&ast.ExpressionStatement{Token: tok, Expression: value},
+		},
+	}
+
+	return arm
+}
+
+func (p *Parser) parsePattern() ast.Pattern {
+	switch p.curToken.Type {
+	case token.IDENT:
+		return &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+	case token.INT, token.FLOAT, token.STRING, token.TRUE, token.FALSE, token.MINUS:
+		return p.parseLiteralPattern()
+	case token.LBRACKET:
+		return p.parseArrayPattern()
+	case token.LBRACE:
+		return p.parseHashPattern()
+	default:
+		p.addError(InvalidPattern, p.curToken, nil,
+			"invalid pattern: %s", p.curToken.Literal)
+		return nil
+	}
+}
+
+func (p *Parser) parseLiteralPattern() ast.Pattern {
+	tok := p.curToken
+
+	value := p.parseExpression(PREFIX)
+	if value == nil {
+		return nil
+	}
+
+	switch value := value.(type) {
+	case *ast.IntegerLiteral, *ast.FloatLiteral, *ast.StringLiteral, *ast.Boolean:
+		return &ast.LiteralPattern{Value: value}
+	case *ast.PrefixExpression:
+		switch value.Right.(type) {
+		case *ast.IntegerLiteral, *ast.FloatLiteral:
+			return &ast.LiteralPattern{Value: value}
+		}
+	}
+
+	// value may be missing parts after an error, so it is not printed
+	p.addError(InvalidPattern, tok, nil, "invalid pattern: %s", tok.Literal)
+	return nil
+}
+
+func (p *Parser) parseArrayPattern() ast.Pattern {
+	pattern := &ast.ArrayPattern{Token: p.curToken}
+
+	for !p.peekTokenIs(token.RBRACKET) {
+		p.nextToken()
+
+		if p.curTokenIs(token.ELLIPSIS) {
+			if !p.expectPeek(token.IDENT) {
+				return nil
+			}
+			pattern.Rest = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+			break
+		}
+
+		element := p.parsePattern()
+		if element == nil {
+			return nil
+		}
+		pattern.Elements = append(pattern.Elements, element)
+
+		if !p.peekTokenIs(token.RBRACKET) && !p.expectPeek(token.COMMA) {
+			return nil
+		}
+	}
+
+	if !p.expectPeek(token.RBRACKET) {
+		return nil
+	}
+	pattern.Rbracket = p.curToken
+
+	return pattern
+}
+
+// parseHashPattern parses {"key": pattern, ...}. A bare identifier key is
+// taken as a string, so {name: n} matches the "name" key and {name} is
+// shorthand for {name: name}.
+func (p *Parser) parseHashPattern() ast.Pattern {
+	pattern := &ast.HashPattern{Token: p.curToken}
+
+	for !p.peekTokenIs(token.RBRACE) {
+		p.nextToken()
+
+		pair := &ast.HashPatternPair{}
+
+		if p.curTokenIs(token.IDENT) {
+			ident := &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+			pair.Key = &ast.StringLiteral{Token: p.curToken, Value: ident.Value}
+			pair.Value = ident
+		} else {
+			key, ok := p.parseLiteralPattern().(*ast.LiteralPattern)
+			if !ok {
+				return nil
+			}
+			pair.Key = key.Value
+		}
+
+		if p.peekTokenIs(token.COLON) {
+			p.nextToken()
+			p.nextToken()
+			pair.Value = p.parsePattern()
+		}
+
+		if pair.Value == nil {
+			if !p.recovering {
+				p.peekError(token.COLON)
+			}
+			return nil
+		}
+		pattern.Pairs = append(pattern.Pairs, pair)
+
+		if !p.peekTokenIs(token.RBRACE) && !p.expectPeek(token.COMMA) {
+			return nil
+		}
+	}
+
+	if !p.expectPeek(token.RBRACE) {
+		return nil
+	}
+	pattern.Rbrace = p.curToken
+
+	return pattern
+}
+
 func (p *Parser) parseBlockStatement() *ast.BlockStatement {
 	block := &ast.BlockStatement{Token: p.curToken}
 	block.Statements = []ast.Statement{}
 
+	p.blockDepth++
+	defer func() { p.blockDepth-- }()
+
 	p.nextToken()
 
-	for !p.curTokenIs(token.RBRACE) && !p.curTokenIs(token.EOF) {
+	for !p.curTokenIs(token.RBRACE) && !p.curTokenIs(token.EOF) && !p.gaveUp {
+		depth := p.braceDepth
 		stmt := p.parseStatement()
 		if stmt != nil {
 			block.Statements = append(block.Statements, stmt)
 		}
+		if p.recovering {
+			p.synchronize(depth)
+		}
 		p.nextToken()
 	}
 
+	block.Rbrace = p.curToken
+
 	return block
 }
 
 func (p *Parser) parseFunctionLiteral() ast.Expression {
 	lit := &ast.FunctionLiteral{Token: p.curToken}
 
-	if !p.expectPeek(token.LPAREN) {
+	if !p.parseFunction(lit) {
 		return nil
 	}
 
-	lit.Parameters = p.parseFunctionParameters()
+	return lit
+}
+
+// parseFunction parses the parameters and body of a function literal or
+// declaration, starting with the token before the opening parenthesis.
+func (p *Parser) parseFunction(lit *ast.FunctionLiteral) bool {
+	if !p.expectPeek(token.LPAREN) {
+		return false
+	}
+
+	if !p.parseFunctionParameters(lit) {
+		return false
+	}
 
 	if !p.expectPeek(token.LBRACE) {
-		return nil
+		return false
 	}
 
+	// break and continue cannot reach a loop outside of the function
+	loopDepth := p.loopDepth
+	p.loopDepth = 0
 	lit.Body = p.parseBlockStatement()
+	p.loopDepth = loopDepth
 
-	return lit
+	return true
 }
 
-func (p *Parser) parseFunctionParameters() []*ast.Identifier {
-	identifiers := []*ast.Identifier{}
+func (p *Parser) parseImportExpression() ast.Expression {
+	expression := &ast.ImportExpression{Token: p.curToken}
 
-	if p.peekTokenIs(token.RPAREN) {
-		p.nextToken()
-		return identifiers
+	if !p.expectPeek(token.STRING) {
+		return nil
 	}
 
-	p.nextToken()
+	expression.Path = &ast.StringLiteral{Token: p.curToken, Value: p.curToken.Literal}
 
-	ident := &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
-	identifiers = append(identifiers, ident)
+	return expression
+}
 
-	for p.peekTokenIs(token.COMMA) {
-		p.nextToken()
+func (p *Parser) parseMacroLiteral() ast.Expression {
+	lit := &ast.MacroLiteral{Token: p.curToken}
+
+	if !p.expectPeek(token.LPAREN) {
+		return nil
+	}
+
+	lit.Parameters = []*ast.Identifier{}
+	for !p.peekTokenIs(token.RPAREN) {
+		if !p.expectPeek(token.IDENT) {
+			return nil
+		}
+		lit.Parameters = append(lit.Parameters,
+			&ast.Identifier{Token: p.curToken, Value: p.curToken.Literal})
+
+		if !p.peekTokenIs(token.COMMA) {
+			break
+		}
 		p.nextToken()
-		ident := &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
-		identifiers = append(identifiers, ident)
 	}
 
 	if !p.expectPeek(token.RPAREN) {
 		return nil
 	}
 
-	return identifiers
+	if !p.expectPeek(token.LBRACE) {
+		return nil
+	}
+
+	lit.Body = p.parseBlockStatement()
+
+	return lit
+}
+
+func (p *Parser) parseFunctionParameters(lit *ast.FunctionLiteral) bool {
+	lit.Parameters = []*ast.Identifier{}
+	lit.Defaults = make(map[string]ast.Expression)
+
+	seen := map[string]bool{}
+
+	for !p.peekTokenIs(token.RPAREN) {
+		if p.peekTokenIs(token.ELLIPSIS) {
+			p.nextToken()
+			if !p.expectPeek(token.IDENT) || !p.checkParameterName(seen) {
+				return false
+			}
+			// the rest parameter must be the last one
+			lit.Rest = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+			break
+		}
+
+		if !p.expectPeek(token.IDENT) || !p.checkParameterName(seen) {
+			return false
+		}
+		ident := &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+		lit.Parameters = append(lit.Parameters, ident)
+
+		if p.peekTokenIs(token.ASSIGN) {
+			p.nextToken()
+			p.nextToken()
+			lit.Defaults[ident.Value] = p.parseExpression(LOWEST)
+		} else if len(lit.Defaults) > 0 {
+			p.addError(InvalidParameter, ident.Token, nil,
+				"parameter %s without a default follows a parameter with one", ident.Value)
+			return false
+		}
+
+		if !p.peekTokenIs(token.COMMA) {
+			break
+		}
+		p.nextToken()
+	}
+
+	return p.expectPeek(token.RPAREN)
+}
+
+// checkParameterName reports an error if the parameter in curToken has the
+// name of an earlier one, and adds it to seen otherwise.
+func (p *Parser) checkParameterName(seen map[string]bool) bool {
+	if seen[p.curToken.Literal] {
+		p.addError(InvalidParameter, p.curToken, nil,
+			"duplicate parameter %s", p.curToken.Literal)
+		return false
+	}
+	seen[p.curToken.Literal] = true
+	return true
 }
 
 func (p *Parser) parseCallExpression(function ast.Expression) ast.Expression {
 	exp := &ast.CallExpression{Token: p.curToken, Function: function}
-	exp.Arguments = p.parseExpressionList(token.RPAREN)
+	exp.Arguments = []ast.Expression{}
+
+	for !p.peekTokenIs(token.RPAREN) {
+		p.nextToken()
+
+		if p.curTokenIs(token.IDENT) && p.peekTokenIs(token.COLON) {
+			if !p.parseKeywordArgument(exp) {
+				return exp
+			}
+		} else if len(exp.Keywords) > 0 {
+			p.addError(UnexpectedToken, p.curToken, nil,
+				"positional argument after keyword argument")
+			return exp
+		} else {
+			exp.Arguments = append(exp.Arguments, p.parseExpression(LOWEST))
+		}
+
+		if !p.peekTokenIs(token.COMMA) {
+			break
+		}
+		p.nextToken()
+	}
+
+	if !p.expectPeek(token.RPAREN) {
+		return exp
+	}
+	exp.Rparen = p.curToken
 	return exp
 }
 
+func (p *Parser) parseKeywordArgument(exp *ast.CallExpression) bool {
+	name := &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+
+	for _, k := range exp.Keywords {
+		if k.Name.Value == name.Value {
+			p.addError(UnexpectedToken, p.curToken, nil,
+				"duplicate keyword argument: %s", name.Value)
+			return false
+		}
+	}
+
+	p.nextToken()
+	p.nextToken()
+
+	exp.Keywords = append(exp.Keywords,
+		&ast.KeywordArgument{Name: name, Value: p.parseExpression(LOWEST)})
+	return true
+}
+
 func (p *Parser) parseExpressionList(end token.TokenType) []ast.Expression {
 	list := []ast.Expression{}
 
@@ -436,6 +1184,7 @@ func (p *Parser) parseArrayLiteral() ast.Expression {
 	array := &ast.ArrayLiteral{Token: p.curToken}
 
 	array.Elements = p.parseExpressionList(token.RBRACKET)
+	array.Rbracket = p.curToken
 
 	return array
 }
@@ -449,13 +1198,14 @@ func (p *Parser) parseIndexExpression(left ast.Expression) ast.Expression {
 	if !p.expectPeek(token.RBRACKET) {
 		return nil
 	}
+	exp.Rbracket = p.curToken
 
 	return exp
 }
 
 func (p *Parser) parseHashLiteral() ast.Expression {
 	hash := &ast.HashLiteral{Token: p.curToken}
-	hash.Pairs = make(map[ast.Expression]ast.Expression)
+	hash.Pairs = []*ast.HashLiteralPair{}
 
 	for !p.peekTokenIs(token.RBRACE) {
 		p.nextToken()
@@ -468,7 +1218,7 @@ func (p *Parser) parseHashLiteral() ast.Expression {
 		p.nextToken()
 		value := p.parseExpression(LOWEST)
 
-		hash.Pairs[key] = value
+		hash.Pairs = append(hash.Pairs, &ast.HashLiteralPair{Key: key, Value: value})
 
 		if !p.peekTokenIs(token.RBRACE) && !p.expectPeek(token.COMMA) {
 			return nil
@@ -478,6 +1228,7 @@ func (p *Parser) parseHashLiteral() ast.Expression {
 	if !p.expectPeek(token.RBRACE) {
 		return nil
 	}
+	hash.Rbrace = p.curToken
 
 	return hash
 }
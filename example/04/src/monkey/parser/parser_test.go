@@ -4,6 +4,8 @@ import (
 	"fmt"
 	"monkey/ast"
 	"monkey/lexer"
+	"monkey/token"
+	"strings"
 	"testing"
 )
 
@@ -139,6 +141,121 @@ func TestIntegerLiteralExpression(t *testing.T) {
 	}
 }
 
+func TestIntegerLiteralForms(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected int64
+	}{
+		{"0xff", 255},
+		{"0XFF", 255},
+		{"0o17", 15},
+		{"0b1010", 10},
+		{"1_000_000", 1000000},
+		{"0b_1111_0000", 240},
+		{"0", 0},
+		{"10", 10},
+		{"9223372036854775807", 9223372036854775807},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		stmt := program.Statements[0].(*ast.ExpressionStatement)
+		literal, ok := stmt.Expression.(*ast.IntegerLiteral)
+		if !ok {
+			t.Fatalf("exp not *ast.IntegerLiteral. got=%T", stmt.Expression)
+		}
+		if literal.Value != tt.expected {
+			t.Errorf("literal.Value not %d. got=%d", tt.expected, literal.Value)
+		}
+	}
+
+	for _, input := range []string{"0b102", "1__0", "0x", "017", "00", "0_7"} {
+		l := lexer.New(input)
+		p := New(l)
+		p.ParseProgram()
+
+		if len(p.Errors()) == 0 {
+			t.Errorf("expected parser error for %q", input)
+		}
+	}
+}
+
+func TestBigIntegerLiteral(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"9223372036854775808", "9223372036854775808"},
+		{"100_000_000_000_000_000_000", "100000000000000000000"},
+		{"0x1_0000_0000_0000_0000", "18446744073709551616"},
+		{"0b1" + strings.Repeat("0", 64), "18446744073709551616"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		stmt := program.Statements[0].(*ast.ExpressionStatement)
+		literal, ok := stmt.Expression.(*ast.IntegerLiteral)
+		if !ok {
+			t.Fatalf("exp not *ast.IntegerLiteral. got=%T", stmt.Expression)
+		}
+		if literal.Big == nil {
+			t.Fatalf("literal.Big is nil for %q", tt.input)
+		}
+		if literal.Big.String() != tt.expected {
+			t.Errorf("literal.Big not %s. got=%s", tt.expected, literal.Big)
+		}
+		if literal.String() != tt.input {
+			t.Errorf("literal.String() not %q. got=%q", tt.input, literal.String())
+		}
+	}
+}
+
+func TestFloatLiteralExpression(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected float64
+	}{
+		{"3.14;", 3.14},
+		{"0.5", 0.5},
+		{"1e3", 1000},
+		{"2.5E-3", 0.0025},
+		{"6.02e+23", 6.02e23},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if len(program.Statements) != 1 {
+			t.Fatalf("program has not enough statements. got=%d",
+				len(program.Statements))
+		}
+		stmt, ok := program.Statements[0].(*ast.ExpressionStatement)
+		if !ok {
+			t.Fatalf("program.Statements[0] is not ast.ExpressionStatement. got=%T",
+				program.Statements[0])
+		}
+
+		literal, ok := stmt.Expression.(*ast.FloatLiteral)
+		if !ok {
+			t.Fatalf("exp not *ast.FloatLiteral. got=%T", stmt.Expression)
+		}
+		if literal.Value != tt.expected {
+			t.Errorf("literal.Value not %g. got=%g", tt.expected, literal.Value)
+		}
+	}
+}
+
 func TestParsingPrefixExpressions(t *testing.T) {
 	prefixTests := []struct {
 		input    string
@@ -210,6 +327,16 @@ func TestParsingInfixExpressions(t *testing.T) {
 		{"true == true", true, "==", true},
 		{"true != false", true, "!=", false},
 		{"false == false", false, "==", false},
+		{"5 <= 5;", 5, "<=", 5},
+		{"5 >= 5;", 5, ">=", 5},
+		{"5 % 5;", 5, "%", 5},
+		{"true && false", true, "&&", false},
+		{"true || false", true, "||", false},
+		{"5 & 5;", 5, "&", 5},
+		{"5 | 5;", 5, "|", 5},
+		{"5 ^ 5;", 5, "^", 5},
+		{"5 << 5;", 5, "<<", 5},
+		{"5 >> 5;", 5, ">>", 5},
 	}
 
 	for _, tt := range infixTests {
@@ -349,6 +476,34 @@ func TestOperatorPrecedenceParsing(t *testing.T) {
 			"add(a * b[2], b[1], 2 * [1, 2][1])",
 			"add((a * (b[2])), (b[1]), (2 * ([1, 2][1])))",
 		},
+		{
+			"a || b && c",
+			"(a || (b && c))",
+		},
+		{
+			"a && b || c && d",
+			"((a && b) || (c && d))",
+		},
+		{
+			"a < b == b >= c && !d",
+			"(((a < b) == (b >= c)) && (!d))",
+		},
+		{
+			"a + b % c - d",
+			"((a + (b % c)) - d)",
+		},
+		{
+			"a | b & c ^ d",
+			"((a | (b & c)) ^ d)",
+		},
+		{
+			"1 << 2 + 3 >> 1",
+			"((1 << 2) + (3 >> 1))",
+		},
+		{
+			"~a & b",
+			"((~a) & b)",
+		},
 	}
 
 	for _, tt := range tests {
@@ -509,61 +664,15 @@ func TestIfElseExpression(t *testing.T) {
 	}
 }
 
-func TestFunctionLiteralParsing(t *testing.T) {
-	input := `fn(x, y) { x + y; }`
-
-	l := lexer.New(input)
-	p := New(l)
-	program := p.ParseProgram()
-	checkParserErrors(t, p)
-
-	if len(program.Statements) != 1 {
-		t.Fatalf("program.Statements does not contain %d statements. got=%d\n",
-			1, len(program.Statements))
-	}
-
-	stmt, ok := program.Statements[0].(*ast.ExpressionStatement)
-	if !ok {
-		t.Fatalf("program.Statements[0] is not ast.ExpressionStatement. got=%T",
-			program.Statements[0])
-	}
-
-	function, ok := stmt.Expression.(*ast.FunctionLiteral)
-	if !ok {
-		t.Fatalf("stmt.Expression is not ast.FunctionLiteral. got=%T",
-			stmt.Expression)
-	}
-
-	if len(function.Parameters) != 2 {
-		t.Fatalf("function literal parameters wrong. want 2, got=%d\n",
-			len(function.Parameters))
-	}
-
-	testLiteralExpression(t, function.Parameters[0], "x")
-	testLiteralExpression(t, function.Parameters[1], "y")
-
-	if len(function.Body.Statements) != 1 {
-		t.Fatalf("function.Body.Statements has not 1 statements. got=%d\n",
-			len(function.Body.Statements))
-	}
-
-	bodyStmt, ok := function.Body.Statements[0].(*ast.ExpressionStatement)
-	if !ok {
-		t.Fatalf("function body stmt is not ast.ExpressionStatement. got=%T",
-			function.Body.Statements[0])
-	}
-
-	testInfixExpression(t, bodyStmt.Expression, "x", "+", "y")
-}
-
-func TestFunctionParameterParsing(t *testing.T) {
+func TestDestructuringLetStatements(t *testing.T) {
 	tests := []struct {
-		input          string
-		expectedParams []string
+		input    string
+		expected string
 	}{
-		{input: "fn() {};", expectedParams: []string{}},
-		{input: "fn(x) {};", expectedParams: []string{"x"}},
-		{input: "fn(x, y, z) {};", expectedParams: []string{"x", "y", "z"}},
+		{"let [a, b] = arr;", "let [a, b] = arr;"},
+		{"let [a, _, ...rest] = [1, 2, 3];", "let [a, _, ...rest] = [1, 2, 3];"},
+		{"let {name, age} = person;", "let {name: name, age: age} = person;"},
+		{`let {"pos": [x, y], id: n} = p;`, "let {pos: [x, y], id: n} = p;"},
 	}
 
 	for _, tt := range tests {
@@ -572,79 +681,79 @@ func TestFunctionParameterParsing(t *testing.T) {
 		program := p.ParseProgram()
 		checkParserErrors(t, p)
 
-		stmt := program.Statements[0].(*ast.ExpressionStatement)
-		function := stmt.Expression.(*ast.FunctionLiteral)
+		stmt, ok := program.Statements[0].(*ast.LetStatement)
+		if !ok {
+			t.Fatalf("program.Statements[0] is not ast.LetStatement. got=%T",
+				program.Statements[0])
+		}
 
-		if len(function.Parameters) != len(tt.expectedParams) {
-			t.Errorf("length parameters wrong. want %d, got=%d\n",
-				len(tt.expectedParams), len(function.Parameters))
+		if stmt.Name != nil || stmt.Pattern == nil {
+			t.Fatalf("stmt.Pattern not set. Name=%v, Pattern=%v", stmt.Name, stmt.Pattern)
 		}
 
-		for i, ident := range tt.expectedParams {
-			testLiteralExpression(t, function.Parameters[i], ident)
+		if stmt.String() != tt.expected {
+			t.Errorf("expected=%q, got=%q", tt.expected, stmt.String())
 		}
 	}
 }
 
-func TestCallExpressionParsing(t *testing.T) {
-	input := "add(1, 2 * 3, 4 + 5);"
+func TestElseIfExpression(t *testing.T) {
+	input := `if (x < y) { x } else if (x > y) { y } else { 0 }`
 
 	l := lexer.New(input)
 	p := New(l)
 	program := p.ParseProgram()
 	checkParserErrors(t, p)
 
-	if len(program.Statements) != 1 {
-		t.Fatalf("program.Statements does not contain %d statements. got=%d\n",
-			1, len(program.Statements))
+	stmt := program.Statements[0].(*ast.ExpressionStatement)
+	exp, ok := stmt.Expression.(*ast.IfExpression)
+	if !ok {
+		t.Fatalf("stmt.Expression is not ast.IfExpression. got=%T", stmt.Expression)
 	}
 
-	stmt, ok := program.Statements[0].(*ast.ExpressionStatement)
+	if len(exp.Alternative.Statements) != 1 {
+		t.Fatalf("exp.Alternative.Statements does not contain 1 statements. got=%d\n",
+			len(exp.Alternative.Statements))
+	}
+
+	alternative, ok := exp.Alternative.Statements[0].(*ast.ExpressionStatement)
 	if !ok {
-		t.Fatalf("stmt is not ast.ExpressionStatement. got=%T",
-			program.Statements[0])
+		t.Fatalf("Statements[0] is not ast.ExpressionStatement. got=%T",
+			exp.Alternative.Statements[0])
 	}
 
-	exp, ok := stmt.Expression.(*ast.CallExpression)
+	elseIf, ok := alternative.Expression.(*ast.IfExpression)
 	if !ok {
-		t.Fatalf("stmt.Expression is not ast.CallExpression. got=%T",
-			stmt.Expression)
+		t.Fatalf("alternative is not ast.IfExpression. got=%T", alternative.Expression)
 	}
 
-	if !testIdentifier(t, exp.Function, "add") {
+	if !testInfixExpression(t, elseIf.Condition, "x", ">", "y") {
 		return
 	}
 
-	if len(exp.Arguments) != 3 {
-		t.Fatalf("wrong length of arguments. got=%d", len(exp.Arguments))
+	if elseIf.Alternative == nil {
+		t.Fatalf("else-if has no alternative")
 	}
 
-	testLiteralExpression(t, exp.Arguments[0], 1)
-	testInfixExpression(t, exp.Arguments[1], 2, "*", 3)
-	testInfixExpression(t, exp.Arguments[2], 4, "+", 5)
+	if exp.End() != elseIf.End() {
+		t.Errorf("exp.End() wrong. expected=%s, got=%s", elseIf.End(), exp.End())
+	}
 }
 
-func TestCallExpressionParameterParsing(t *testing.T) {
+func TestMatchExpressionParsing(t *testing.T) {
 	tests := []struct {
-		input         string
-		expectedIdent string
-		expectedArgs  []string
+		input    string
+		expected string
 	}{
-		{
-			input:         "add();",
-			expectedIdent: "add",
-			expectedArgs:  []string{},
-		},
-		{
-			input:         "add(1);",
-			expectedIdent: "add",
-			expectedArgs:  []string{"1"},
-		},
-		{
-			input:         "add(1, 2 * 3, 4 + 5);",
-			expectedIdent: "add",
-			expectedArgs:  []string{"1", "(2 * 3)", "(4 + 5)"},
-		},
+		{"match (x) { 1 => a, _ => b }", "match (x) { 1 => a, _ => b }"},
+		{"match (x) { -1 => a, 2.5 => b, }", "match (x) { (-1) => a, 2.5 => b }"},
+		{`match (x) { "s" => 1, true => 2 }`, "match (x) { s => 1, true => 2 }"},
+		{"match (x) { n => { n * 2 } _ => 0 }", "match (x) { n => (n * 2), _ => 0 }"},
+		{"match (xs) { [] => 0, [a] => a, [a, _, ...rest] => a }",
+			"match (xs) { [] => 0, [a] => a, [a, _, ...rest] => a }"},
+		{`match (h) { {name, "age": [a]} => a, {1: x} => x }`,
+			"match (h) { {name: name, age: [a]} => a, {1: x} => x }"},
+		{"match (x) { y => match (y) { _ => y } }", "match (x) { y => match (y) { _ => y } }"},
 	}
 
 	for _, tt := range tests {
@@ -654,51 +763,819 @@ func TestCallExpressionParameterParsing(t *testing.T) {
 		checkParserErrors(t, p)
 
 		stmt := program.Statements[0].(*ast.ExpressionStatement)
-		exp, ok := stmt.Expression.(*ast.CallExpression)
+		exp, ok := stmt.Expression.(*ast.MatchExpression)
 		if !ok {
-			t.Fatalf("stmt.Expression is not ast.CallExpression. got=%T",
+			t.Fatalf("stmt.Expression is not ast.MatchExpression. got=%T",
 				stmt.Expression)
 		}
 
-		if !testIdentifier(t, exp.Function, tt.expectedIdent) {
-			return
+		if exp.String() != tt.expected {
+			t.Errorf("expected=%q, got=%q", tt.expected, exp.String())
 		}
+	}
+}
 
-		if len(exp.Arguments) != len(tt.expectedArgs) {
-			t.Fatalf("wrong number of arguments. want=%d, got=%d",
-				len(tt.expectedArgs), len(exp.Arguments))
+func TestInvalidPatterns(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"match (x) { x + 1 => 1 }", "1:15: expected next token to be =>, got + instead"},
+		{"match (x) { fn() {} => 1 }", "1:13: invalid pattern: fn"},
+		{"match (x) { -a => 1 }", "1:13: invalid pattern: -"},
+		{"match (x) { -) => 1 }", "1:14: no prefix parse function for ) found"},
+		{`match (x) { -"${)}" => 1 }`, "1:17: no prefix parse function for ) found"},
+		{"match (x) { [...] => 1 }", "1:17: expected next token to be IDENT, got ] instead"},
+		{`match (x) { {"a"} => 1 }`, "1:17: expected next token to be :, got } instead"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		p.ParseProgram()
+
+		errors := p.Errors()
+		if len(errors) == 0 {
+			t.Errorf("no errors for %q", tt.input)
+			continue
 		}
 
-		for i, arg := range tt.expectedArgs {
-			if exp.Arguments[i].String() != arg {
-				t.Errorf("argument %d wrong. want=%q, got=%q", i,
-					arg, exp.Arguments[i].String())
-			}
+		if errors[0].Error() != tt.expected {
+			t.Errorf("wrong error. expected=%q, got=%q",
+				tt.expected, errors[0].Error())
 		}
 	}
 }
 
-func TestStringLiteralExpression(t *testing.T) {
-	input := `"hello world";`
+func TestTryExpressionParsing(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"try { a } catch (e) { b }", "try a catch (e) b"},
+		{"try { a } catch { b }", "try a catch b"},
+		{"try { a } finally { c }", "try a finally c"},
+		{"try { a } catch (e) { b } finally { c }", "try a catch (e) b finally c"},
+		{"let x = try { f() } catch (e) { 0 };", "let x = try f() catch (e) 0;"},
+		{"throw 1 + 2;", "throw (1 + 2);"},
+		{`throw {"kind": "oops"}`, "throw {kind:oops};"},
+	}
 
-	l := lexer.New(input)
-	p := New(l)
-	program := p.ParseProgram()
-	checkParserErrors(t, p)
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
 
-	stmt := program.Statements[0].(*ast.ExpressionStatement)
-	literal, ok := stmt.Expression.(*ast.StringLiteral)
-	if !ok {
-		t.Fatalf("exp not *ast.StringLiteral. got=%T", stmt.Expression)
+		if program.String() != tt.expected {
+			t.Errorf("expected=%q, got=%q", tt.expected, program.String())
+		}
 	}
+}
 
-	if literal.Value != "hello world" {
-		t.Errorf("literal.Value not %q. got=%q", "hello world", literal.Value)
+func TestInvalidTryExpressions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"try { a }", "1:10: expected catch or finally after try block, got EOF instead"},
+		{"try { a } catch (1) { b }", "1:18: expected next token to be IDENT, got INT instead"},
+		{"try { a } catch (e { b }", "1:20: expected next token to be ), got { instead"},
+		{"try a", "1:5: expected next token to be {, got IDENT instead"},
 	}
-}
 
-func TestParsingEmptyArrayLiterals(t *testing.T) {
-	input := "[]"
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		p.ParseProgram()
+
+		errors := p.Errors()
+		if len(errors) == 0 {
+			t.Errorf("no errors for %q", tt.input)
+			continue
+		}
+
+		if errors[0].Error() != tt.expected {
+			t.Errorf("wrong error. expected=%q, got=%q",
+				tt.expected, errors[0].Error())
+		}
+	}
+}
+
+func TestImportAndExport(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`let m = import "lib/math";`, `let m = import "lib/math";`},
+		{`import "m"["x"]`, `(import "m"[x])`},
+		{`export let x = 1;`, `export let x = 1;`},
+		{`export let [a, b] = f();`, `export let [a, b] = f();`},
+		{`export fn add(a, b) { a + b }`, `export fn add(a, b) (a + b)`},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if program.String() != tt.expected {
+			t.Errorf("expected=%q, got=%q", tt.expected, program.String())
+		}
+	}
+}
+
+func TestInvalidImportAndExport(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`import m`, "1:8: expected next token to be STRING, got IDENT instead"},
+		{`export 1`, "1:8: expected let or fn declaration after export, got INT instead"},
+		{`export fn(x) { x }`, "1:8: expected let or fn declaration after export, got FUNCTION instead"},
+		{`if (true) { export let x = 1; }`, "1:13: export is only allowed at the top level"},
+		{`fn f() { export let x = 1; }`, "1:10: export is only allowed at the top level"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		p.ParseProgram()
+
+		errors := p.Errors()
+		if len(errors) == 0 {
+			t.Errorf("no errors for %q", tt.input)
+			continue
+		}
+
+		if errors[0].Error() != tt.expected {
+			t.Errorf("wrong error. expected=%q, got=%q",
+				tt.expected, errors[0].Error())
+		}
+	}
+}
+
+func TestWhileStatement(t *testing.T) {
+	input := `while (x < y) { x; break; }`
+
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	if len(program.Statements) != 1 {
+		t.Fatalf("program.Statements does not contain %d statements. got=%d\n",
+			1, len(program.Statements))
+	}
+
+	stmt, ok := program.Statements[0].(*ast.WhileStatement)
+	if !ok {
+		t.Fatalf("program.Statements[0] is not ast.WhileStatement. got=%T",
+			program.Statements[0])
+	}
+
+	if !testInfixExpression(t, stmt.Condition, "x", "<", "y") {
+		return
+	}
+
+	if len(stmt.Body.Statements) != 2 {
+		t.Fatalf("body is not 2 statements. got=%d\n",
+			len(stmt.Body.Statements))
+	}
+
+	if _, ok := stmt.Body.Statements[1].(*ast.BreakStatement); !ok {
+		t.Fatalf("Statements[1] is not ast.BreakStatement. got=%T",
+			stmt.Body.Statements[1])
+	}
+}
+
+func TestForStatement(t *testing.T) {
+	input := `for (item in items) { if (item) { continue; } item }`
+
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	if len(program.Statements) != 1 {
+		t.Fatalf("program.Statements does not contain %d statements. got=%d\n",
+			1, len(program.Statements))
+	}
+
+	stmt, ok := program.Statements[0].(*ast.ForStatement)
+	if !ok {
+		t.Fatalf("program.Statements[0] is not ast.ForStatement. got=%T",
+			program.Statements[0])
+	}
+
+	if !testIdentifier(t, stmt.Variable, "item") {
+		return
+	}
+
+	if !testIdentifier(t, stmt.Iterable, "items") {
+		return
+	}
+
+	if len(stmt.Body.Statements) != 2 {
+		t.Fatalf("body is not 2 statements. got=%d\n",
+			len(stmt.Body.Statements))
+	}
+
+	if stmt.String() != "for (item in items) ifitem continue;item" {
+		t.Errorf("stmt.String() wrong. got=%q", stmt.String())
+	}
+}
+
+func TestLoopStatementsWithSemicolon(t *testing.T) {
+	tests := []string{
+		`let i = 0; while (i < 3) { i += 1 }; i;`,
+		`let i = 0; for (x in [1, 2]) { i += x }; i;`,
+	}
+
+	for _, input := range tests {
+		l := lexer.New(input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if len(program.Statements) != 3 {
+			t.Fatalf("program.Statements does not contain %d statements. got=%d\n",
+				3, len(program.Statements))
+		}
+
+		stmt, ok := program.Statements[2].(*ast.ExpressionStatement)
+		if !ok {
+			t.Fatalf("program.Statements[2] is not ast.ExpressionStatement. got=%T",
+				program.Statements[2])
+		}
+
+		if !testIdentifier(t, stmt.Expression, "i") {
+			return
+		}
+	}
+}
+
+func TestAssignExpressionParsing(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"x = 5;", "x = 5"},
+		{"x += 1 + 2;", "x += (1 + 2)"},
+		{"x -= y * 2", "x -= (y * 2)"},
+		{"x *= 2", "x *= 2"},
+		{"x /= 2", "x /= 2"},
+		{"x %= 2", "x %= 2"},
+		{"a = b = c", "a = b = c"},
+		{"x = y || z", "x = (y || z)"},
+		{"a[0] = 1", "(a[0]) = 1"},
+		{`h["k"] += a[1]`, "(h[k]) += (a[1])"},
+		{"a[i][j] = b[i] = c", "((a[i])[j]) = (b[i]) = c"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		stmt, ok := program.Statements[0].(*ast.ExpressionStatement)
+		if !ok {
+			t.Fatalf("program.Statements[0] is not ast.ExpressionStatement. got=%T",
+				program.Statements[0])
+		}
+
+		exp, ok := stmt.Expression.(*ast.AssignExpression)
+		if !ok {
+			t.Fatalf("stmt.Expression is not ast.AssignExpression. got=%T",
+				stmt.Expression)
+		}
+
+		if exp.String() != tt.expected {
+			t.Errorf("expected=%q, got=%q", tt.expected, exp.String())
+		}
+	}
+}
+
+func TestInvalidAssignmentTarget(t *testing.T) {
+	tests := []struct {
+		input    string
+		code     ErrorCode
+		expected string
+	}{
+		{"1 + 2 = 3;", InvalidAssignment, "1:7: invalid target on the left of ="},
+		{"f() += 1;", InvalidAssignment, "1:5: invalid target on the left of +="},
+		{`"abc\q" = 1`, IllegalToken, "1:1: illegal token: invalid escape sequence \\q"},
+		{"(1 +) = 2", MissingExpression, "1:5: no prefix parse function for ) found"},
+		{"A!#=", IllegalToken, "1:3: illegal token: #"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		p.ParseProgram()
+
+		errors := p.Errors()
+		if len(errors) != 1 {
+			t.Errorf("wrong number of errors for %q. got=%v", tt.input, errors)
+			continue
+		}
+
+		if errors[0].Code != tt.code {
+			t.Errorf("wrong error code for %q. got=%q", tt.input, errors[0].Code)
+		}
+
+		if errors[0].Error() != tt.expected {
+			t.Errorf("wrong error. expected=%q, got=%q", tt.expected, errors[0].Error())
+		}
+	}
+}
+
+func TestLoopControlOutsideLoop(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"break;", "1:1: break outside of a loop"},
+		{"if (true) { continue; }", "1:13: continue outside of a loop"},
+		{"while (true) { let f = fn() { break; }; }", "1:31: break outside of a loop"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		p.ParseProgram()
+
+		errors := p.Errors()
+		if len(errors) != 1 {
+			t.Errorf("wrong number of errors for %q. got=%d", tt.input, len(errors))
+			continue
+		}
+
+		if errors[0].Error() != tt.expected {
+			t.Errorf("wrong error. expected=%q, got=%q",
+				tt.expected, errors[0].Error())
+		}
+	}
+}
+
+func TestFunctionLiteralParsing(t *testing.T) {
+	input := `fn(x, y) { x + y; }`
+
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	if len(program.Statements) != 1 {
+		t.Fatalf("program.Statements does not contain %d statements. got=%d\n",
+			1, len(program.Statements))
+	}
+
+	stmt, ok := program.Statements[0].(*ast.ExpressionStatement)
+	if !ok {
+		t.Fatalf("program.Statements[0] is not ast.ExpressionStatement. got=%T",
+			program.Statements[0])
+	}
+
+	function, ok := stmt.Expression.(*ast.FunctionLiteral)
+	if !ok {
+		t.Fatalf("stmt.Expression is not ast.FunctionLiteral. got=%T",
+			stmt.Expression)
+	}
+
+	if len(function.Parameters) != 2 {
+		t.Fatalf("function literal parameters wrong. want 2, got=%d\n",
+			len(function.Parameters))
+	}
+
+	testLiteralExpression(t, function.Parameters[0], "x")
+	testLiteralExpression(t, function.Parameters[1], "y")
+
+	if len(function.Body.Statements) != 1 {
+		t.Fatalf("function.Body.Statements has not 1 statements. got=%d\n",
+			len(function.Body.Statements))
+	}
+
+	bodyStmt, ok := function.Body.Statements[0].(*ast.ExpressionStatement)
+	if !ok {
+		t.Fatalf("function body stmt is not ast.ExpressionStatement. got=%T",
+			function.Body.Statements[0])
+	}
+
+	testInfixExpression(t, bodyStmt.Expression, "x", "+", "y")
+}
+
+func TestFunctionParameterParsing(t *testing.T) {
+	tests := []struct {
+		input          string
+		expectedParams []string
+	}{
+		{input: "fn() {};", expectedParams: []string{}},
+		{input: "fn(x) {};", expectedParams: []string{"x"}},
+		{input: "fn(x, y, z) {};", expectedParams: []string{"x", "y", "z"}},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		stmt := program.Statements[0].(*ast.ExpressionStatement)
+		function := stmt.Expression.(*ast.FunctionLiteral)
+
+		if len(function.Parameters) != len(tt.expectedParams) {
+			t.Errorf("length parameters wrong. want %d, got=%d\n",
+				len(tt.expectedParams), len(function.Parameters))
+		}
+
+		for i, ident := range tt.expectedParams {
+			testLiteralExpression(t, function.Parameters[i], ident)
+		}
+	}
+}
+
+func TestFunctionStatement(t *testing.T) {
+	input := `fn add(x, y = 1) { x + y } fn(x) { x }(1); let sub = fn(a, b) { a - b };`
+
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	if len(program.Statements) != 3 {
+		t.Fatalf("program.Statements does not contain %d statements. got=%d\n",
+			3, len(program.Statements))
+	}
+
+	stmt, ok := program.Statements[0].(*ast.FunctionStatement)
+	if !ok {
+		t.Fatalf("program.Statements[0] is not ast.FunctionStatement. got=%T",
+			program.Statements[0])
+	}
+
+	if !testIdentifier(t, stmt.Name, "add") {
+		return
+	}
+
+	if stmt.Function.Name != "add" {
+		t.Errorf("function name wrong. got=%q", stmt.Function.Name)
+	}
+
+	if stmt.String() != "fn add(x, y = 1) (x + y)" {
+		t.Errorf("stmt.String() wrong. got=%q", stmt.String())
+	}
+
+	if _, ok := program.Statements[1].(*ast.ExpressionStatement); !ok {
+		t.Fatalf("program.Statements[1] is not ast.ExpressionStatement. got=%T",
+			program.Statements[1])
+	}
+
+	let := program.Statements[2].(*ast.LetStatement)
+	fn, ok := let.Value.(*ast.FunctionLiteral)
+	if !ok {
+		t.Fatalf("let.Value is not ast.FunctionLiteral. got=%T", let.Value)
+	}
+
+	if fn.String() != "fn<sub>(a, b) (a - b)" {
+		t.Errorf("fn.String() wrong. got=%q", fn.String())
+	}
+}
+
+func TestFunctionDefaultAndRestParameters(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"fn(x, y = 2) {}", "fn(x, y = 2) "},
+		{"fn(x = 1 + 2, y = x) {}", "fn(x = (1 + 2), y = x) "},
+		{"fn(...rest) {}", "fn(...rest) "},
+		{"fn(a, b = [], ...rest) {}", "fn(a, b = [], ...rest) "},
+		{"fn(a, b,) {}", "fn(a, b) "},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		stmt := program.Statements[0].(*ast.ExpressionStatement)
+		function, ok := stmt.Expression.(*ast.FunctionLiteral)
+		if !ok {
+			t.Fatalf("stmt.Expression is not ast.FunctionLiteral. got=%T",
+				stmt.Expression)
+		}
+
+		if function.String() != tt.expected {
+			t.Errorf("expected=%q, got=%q", tt.expected, function.String())
+		}
+	}
+}
+
+func TestInvalidFunctionParameters(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"fn(...rest, x) {}", "1:11: expected next token to be ), got , instead"},
+		{"fn(1) {}", "1:4: expected next token to be IDENT, got INT instead"},
+		{"fn(...) {}", "1:7: expected next token to be IDENT, got ) instead"},
+		{"fn(a = 1, b) { a };", "1:11: parameter b without a default follows a parameter with one"},
+		{"fn f(a, b = 2, c, d = 4) { a }", "1:16: parameter c without a default follows a parameter with one"},
+		{"fn(a, a) { a };", "1:7: duplicate parameter a"},
+		{"fn(a, b = 1, ...a) { a };", "1:17: duplicate parameter a"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		p.ParseProgram()
+
+		errors := p.Errors()
+		if len(errors) == 0 {
+			t.Errorf("no errors for %q", tt.input)
+			continue
+		}
+
+		if errors[0].Error() != tt.expected {
+			t.Errorf("wrong error. expected=%q, got=%q",
+				tt.expected, errors[0].Error())
+		}
+	}
+}
+
+func TestCallExpressionKeywordArguments(t *testing.T) {
+	tests := []struct {
+		input            string
+		expected         string
+		expectedArgs     int
+		expectedKeywords []string
+	}{
+		{"f(a: 1)", "f(a: 1)", 0, []string{"a"}},
+		{"f(1, 2, b: x + 1, c: [])", "f(1, 2, b: (x + 1), c: [])", 2, []string{"b", "c"}},
+		{"f({a: 1})", "f({a:1})", 1, []string{}},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		stmt := program.Statements[0].(*ast.ExpressionStatement)
+		exp, ok := stmt.Expression.(*ast.CallExpression)
+		if !ok {
+			t.Fatalf("stmt.Expression is not ast.CallExpression. got=%T",
+				stmt.Expression)
+		}
+
+		if exp.String() != tt.expected {
+			t.Errorf("expected=%q, got=%q", tt.expected, exp.String())
+		}
+
+		if len(exp.Arguments) != tt.expectedArgs {
+			t.Errorf("wrong number of arguments. want=%d, got=%d",
+				tt.expectedArgs, len(exp.Arguments))
+		}
+
+		if len(exp.Keywords) != len(tt.expectedKeywords) {
+			t.Fatalf("wrong number of keywords. want=%d, got=%d",
+				len(tt.expectedKeywords), len(exp.Keywords))
+		}
+
+		for i, name := range tt.expectedKeywords {
+			testIdentifier(t, exp.Keywords[i].Name, name)
+		}
+	}
+}
+
+func TestInvalidKeywordArguments(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"f(a: 1, 2)", "1:9: positional argument after keyword argument"},
+		{"f(a: 1, a: 2)", "1:9: duplicate keyword argument: a"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		p.ParseProgram()
+
+		errors := p.Errors()
+		if len(errors) == 0 {
+			t.Errorf("no errors for %q", tt.input)
+			continue
+		}
+
+		if errors[0].Error() != tt.expected {
+			t.Errorf("wrong error. expected=%q, got=%q",
+				tt.expected, errors[0].Error())
+		}
+	}
+}
+
+func TestMacroLiteralParsing(t *testing.T) {
+	input := `macro(x, y) { x + y; }`
+
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	if len(program.Statements) != 1 {
+		t.Fatalf("program.Statements does not contain %d statements. got=%d\n",
+			1, len(program.Statements))
+	}
+
+	stmt, ok := program.Statements[0].(*ast.ExpressionStatement)
+	if !ok {
+		t.Fatalf("statement is not ast.ExpressionStatement. got=%T",
+			program.Statements[0])
+	}
+
+	macro, ok := stmt.Expression.(*ast.MacroLiteral)
+	if !ok {
+		t.Fatalf("stmt.Expression is not ast.MacroLiteral. got=%T",
+			stmt.Expression)
+	}
+
+	if len(macro.Parameters) != 2 {
+		t.Fatalf("macro literal parameters wrong. want 2, got=%d\n",
+			len(macro.Parameters))
+	}
+
+	testLiteralExpression(t, macro.Parameters[0], "x")
+	testLiteralExpression(t, macro.Parameters[1], "y")
+
+	if len(macro.Body.Statements) != 1 {
+		t.Fatalf("macro.Body.Statements has not 1 statements. got=%d\n",
+			len(macro.Body.Statements))
+	}
+
+	bodyStmt, ok := macro.Body.Statements[0].(*ast.ExpressionStatement)
+	if !ok {
+		t.Fatalf("macro body stmt is not ast.ExpressionStatement. got=%T",
+			macro.Body.Statements[0])
+	}
+
+	testInfixExpression(t, bodyStmt.Expression, "x", "+", "y")
+}
+
+func TestCallExpressionParsing(t *testing.T) {
+	input := "add(1, 2 * 3, 4 + 5);"
+
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	if len(program.Statements) != 1 {
+		t.Fatalf("program.Statements does not contain %d statements. got=%d\n",
+			1, len(program.Statements))
+	}
+
+	stmt, ok := program.Statements[0].(*ast.ExpressionStatement)
+	if !ok {
+		t.Fatalf("stmt is not ast.ExpressionStatement. got=%T",
+			program.Statements[0])
+	}
+
+	exp, ok := stmt.Expression.(*ast.CallExpression)
+	if !ok {
+		t.Fatalf("stmt.Expression is not ast.CallExpression. got=%T",
+			stmt.Expression)
+	}
+
+	if !testIdentifier(t, exp.Function, "add") {
+		return
+	}
+
+	if len(exp.Arguments) != 3 {
+		t.Fatalf("wrong length of arguments. got=%d", len(exp.Arguments))
+	}
+
+	testLiteralExpression(t, exp.Arguments[0], 1)
+	testInfixExpression(t, exp.Arguments[1], 2, "*", 3)
+	testInfixExpression(t, exp.Arguments[2], 4, "+", 5)
+}
+
+func TestCallExpressionParameterParsing(t *testing.T) {
+	tests := []struct {
+		input         string
+		expectedIdent string
+		expectedArgs  []string
+	}{
+		{
+			input:         "add();",
+			expectedIdent: "add",
+			expectedArgs:  []string{},
+		},
+		{
+			input:         "add(1);",
+			expectedIdent: "add",
+			expectedArgs:  []string{"1"},
+		},
+		{
+			input:         "add(1, 2 * 3, 4 + 5);",
+			expectedIdent: "add",
+			expectedArgs:  []string{"1", "(2 * 3)", "(4 + 5)"},
+		},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		stmt := program.Statements[0].(*ast.ExpressionStatement)
+		exp, ok := stmt.Expression.(*ast.CallExpression)
+		if !ok {
+			t.Fatalf("stmt.Expression is not ast.CallExpression. got=%T",
+				stmt.Expression)
+		}
+
+		if !testIdentifier(t, exp.Function, tt.expectedIdent) {
+			return
+		}
+
+		if len(exp.Arguments) != len(tt.expectedArgs) {
+			t.Fatalf("wrong number of arguments. want=%d, got=%d",
+				len(tt.expectedArgs), len(exp.Arguments))
+		}
+
+		for i, arg := range tt.expectedArgs {
+			if exp.Arguments[i].String() != arg {
+				t.Errorf("argument %d wrong. want=%q, got=%q", i,
+					arg, exp.Arguments[i].String())
+			}
+		}
+	}
+}
+
+func TestStringLiteralExpression(t *testing.T) {
+	input := `"hello world";`
+
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	stmt := program.Statements[0].(*ast.ExpressionStatement)
+	literal, ok := stmt.Expression.(*ast.StringLiteral)
+	if !ok {
+		t.Fatalf("exp not *ast.StringLiteral. got=%T", stmt.Expression)
+	}
+
+	if literal.Value != "hello world" {
+		t.Errorf("literal.Value not %q. got=%q", "hello world", literal.Value)
+	}
+}
+
+func TestTemplateLiteralParsing(t *testing.T) {
+	input := `"Hello ${name}, ${1 + 2}!"`
+
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	stmt := program.Statements[0].(*ast.ExpressionStatement)
+	template, ok := stmt.Expression.(*ast.TemplateLiteral)
+	if !ok {
+		t.Fatalf("exp not *ast.TemplateLiteral. got=%T", stmt.Expression)
+	}
+
+	if len(template.Parts) != 5 {
+		t.Fatalf("template.Parts has wrong length. got=%d", len(template.Parts))
+	}
+
+	for i, text := range map[int]string{0: "Hello ", 2: ", ", 4: "!"} {
+		str, ok := template.Parts[i].(*ast.StringLiteral)
+		if !ok {
+			t.Fatalf("template.Parts[%d] not *ast.StringLiteral. got=%T",
+				i, template.Parts[i])
+		}
+		if str.Value != text {
+			t.Errorf("template.Parts[%d] wrong. want=%q, got=%q", i, text, str.Value)
+		}
+	}
+
+	testIdentifier(t, template.Parts[1], "name")
+	testInfixExpression(t, template.Parts[3], 1, "+", 2)
+
+	if template.String() != "Hello ${name}, ${(1 + 2)}!" {
+		t.Errorf("template.String() wrong. got=%q", template.String())
+	}
+}
+
+func TestParsingEmptyArrayLiterals(t *testing.T) {
+	input := "[]"
 
 	l := lexer.New(input)
 	p := New(l)
@@ -805,15 +1682,15 @@ func TestParsingHashLiteralsStringKeys(t *testing.T) {
 		t.Errorf("hash.Pairs has wrong length. got=%d", len(hash.Pairs))
 	}
 
-	for key, value := range hash.Pairs {
-		literal, ok := key.(*ast.StringLiteral)
+	for _, pair := range hash.Pairs {
+		literal, ok := pair.Key.(*ast.StringLiteral)
 		if !ok {
-			t.Errorf("key is not ast.StringLiteral. got=%T", key)
+			t.Errorf("key is not ast.StringLiteral. got=%T", pair.Key)
 			continue
 		}
 
 		expectedValue := expected[literal.String()]
-		testIntegerLiteral(t, value, expectedValue)
+		testIntegerLiteral(t, pair.Value, expectedValue)
 	}
 }
 
@@ -840,15 +1717,15 @@ func TestParsingHashLiteralsBooleanKeys(t *testing.T) {
 		t.Errorf("hash.Pairs has wrong length. got=%d", len(hash.Pairs))
 	}
 
-	for key, value := range hash.Pairs {
-		boolean, ok := key.(*ast.Boolean)
+	for _, pair := range hash.Pairs {
+		boolean, ok := pair.Key.(*ast.Boolean)
 		if !ok {
-			t.Errorf("key is not ast.BooleanLiteral. got=%T", key)
+			t.Errorf("key is not ast.BooleanLiteral. got=%T", pair.Key)
 			continue
 		}
 
 		expectedValue := expected[boolean.String()]
-		testIntegerLiteral(t, value, expectedValue)
+		testIntegerLiteral(t, pair.Value, expectedValue)
 	}
 }
 
@@ -876,16 +1753,16 @@ func TestParsingHashLiteralsIntegerKeys(t *testing.T) {
 		t.Errorf("hash.Pairs has wrong length. got=%d", len(hash.Pairs))
 	}
 
-	for key, value := range hash.Pairs {
-		integer, ok := key.(*ast.IntegerLiteral)
+	for _, pair := range hash.Pairs {
+		integer, ok := pair.Key.(*ast.IntegerLiteral)
 		if !ok {
-			t.Errorf("key is not ast.IntegerLiteral. got=%T", key)
+			t.Errorf("key is not ast.IntegerLiteral. got=%T", pair.Key)
 			continue
 		}
 
 		expectedValue := expected[integer.String()]
 
-		testIntegerLiteral(t, value, expectedValue)
+		testIntegerLiteral(t, pair.Value, expectedValue)
 	}
 }
 
@@ -919,10 +1796,10 @@ func TestParsingHashLiteralsWithExpressions(t *testing.T) {
 		},
 	}
 
-	for key, value := range hash.Pairs {
-		literal, ok := key.(*ast.StringLiteral)
+	for _, pair := range hash.Pairs {
+		literal, ok := pair.Key.(*ast.StringLiteral)
 		if !ok {
-			t.Errorf("key is not ast.StringLiteral. got=%T", key)
+			t.Errorf("key is not ast.StringLiteral. got=%T", pair.Key)
 			continue
 		}
 
@@ -932,7 +1809,259 @@ func TestParsingHashLiteralsWithExpressions(t *testing.T) {
 			continue
 		}
 
-		testFunc(value)
+		testFunc(pair.Value)
+	}
+}
+
+func TestParsingHashLiteralsKeepSourceOrder(t *testing.T) {
+	input := `{"z": 1, "a": 2, "m": 3, "b": 4}`
+
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	stmt := program.Statements[0].(*ast.ExpressionStatement)
+	hash, ok := stmt.Expression.(*ast.HashLiteral)
+	if !ok {
+		t.Fatalf("exp is not ast.HashLiteral. got=%T", stmt.Expression)
+	}
+
+	expected := []string{"z", "a", "m", "b"}
+	if len(hash.Pairs) != len(expected) {
+		t.Fatalf("hash.Pairs has wrong length. got=%d", len(hash.Pairs))
+	}
+
+	for i, pair := range hash.Pairs {
+		if pair.Key.String() != expected[i] {
+			t.Errorf("key %d is not %q. got=%q", i, expected[i], pair.Key.String())
+		}
+		testIntegerLiteral(t, pair.Value, int64(i+1))
+	}
+
+	if hash.String() != `{z:1, a:2, m:3, b:4}` {
+		t.Errorf("hash.String() wrong. got=%q", hash.String())
+	}
+}
+
+func TestParserErrorPositions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"let x 5;", "1:7: expected next token to be =, got INT instead"},
+		{"let x = 1;\n  let = 2;", "2:7: expected next token to be IDENT, got = instead"},
+		{"1 +\n  ;", "2:3: no prefix parse function for ; found"},
+		{`let s = "abc`, "1:9: illegal token: unterminated string literal"},
+		{`let s = "a\qb";`, "1:9: illegal token: invalid escape sequence \\q"},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		p.ParseProgram()
+
+		errors := p.Errors()
+		if len(errors) == 0 {
+			t.Errorf("expected parser errors for %q", tt.input)
+			continue
+		}
+
+		if errors[0].Error() != tt.expected {
+			t.Errorf("wrong error. expected=%q, got=%q",
+				tt.expected, errors[0].Error())
+		}
+	}
+}
+
+func TestParserErrorRecovery(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []string
+	}{
+		{
+			"let = 5; let x 10; let y = 3;",
+			[]string{
+				"1:5: expected next token to be IDENT, got = instead",
+				"1:16: expected next token to be =, got INT instead",
+			},
+		},
+		{
+			`let f = fn(x {
+  x + 1;
+};
+let y = ;
+let z = 3;`,
+			[]string{
+				"1:14: expected next token to be ), got { instead",
+				"4:9: no prefix parse function for ; found",
+			},
+		},
+		{
+			`if (x +) { let a = 1; let b = 2; }
+let c = [1, 2;
+c`,
+			[]string{
+				"1:8: no prefix parse function for ) found",
+				"2:14: expected next token to be ], got ; instead",
+			},
+		},
+		{
+			`let f = fn() {
+  let a = 1 +;
+  let b = 2;
+  b
+};
+f()`,
+			[]string{
+				"2:14: no prefix parse function for ; found",
+			},
+		},
+		{
+			`{"a" 1}; let c = ;`,
+			[]string{
+				"1:6: expected next token to be :, got INT instead",
+				"1:18: no prefix parse function for ; found",
+			},
+		},
+		{
+			`match (x) { -a => { 1 }, _ => 2 }; let d = ;`,
+			[]string{
+				"1:13: invalid pattern: -",
+				"1:44: no prefix parse function for ; found",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		l := lexer.New(tt.input)
+		p := New(l)
+		p.ParseProgram()
+
+		errors := p.Errors()
+		if len(errors) != len(tt.expected) {
+			t.Errorf("wrong number of errors for %q. want=%d, got=%d",
+				tt.input, len(tt.expected), len(errors))
+			for _, err := range errors {
+				t.Errorf("parser error: %q", err)
+			}
+			continue
+		}
+
+		for i, msg := range tt.expected {
+			if errors[i].Error() != msg {
+				t.Errorf("errors[%d] wrong. expected=%q, got=%q",
+					i, msg, errors[i].Error())
+			}
+		}
+	}
+}
+
+func TestParseErrorDetails(t *testing.T) {
+	l := lexer.New("let x 5;")
+	p := New(l)
+	p.ParseProgram()
+
+	errors := p.Errors()
+	if len(errors) != 1 {
+		t.Fatalf("wrong number of errors. got=%d", len(errors))
+	}
+
+	err := errors[0]
+	if err.Code != UnexpectedToken {
+		t.Errorf("err.Code wrong. got=%q", err.Code)
+	}
+	if len(err.Expected) != 1 || err.Expected[0] != token.ASSIGN {
+		t.Errorf("err.Expected wrong. got=%v", err.Expected)
+	}
+	if err.Found.Type != token.INT || err.Found.Literal != "5" {
+		t.Errorf("err.Found wrong. got=%+v", err.Found)
+	}
+	if err.Pos.Line != 1 || err.Pos.Column != 7 {
+		t.Errorf("err.Pos wrong. got=%+v", err.Pos)
+	}
+}
+
+func TestParserErrorLimit(t *testing.T) {
+	input := strings.Repeat("let = 1;\n", MaxErrors+5)
+
+	l := lexer.New(input)
+	p := New(l)
+	p.ParseProgram()
+
+	errors := p.Errors()
+	if len(errors) != MaxErrors+1 {
+		t.Fatalf("wrong number of errors. want=%d, got=%d",
+			MaxErrors+1, len(errors))
+	}
+
+	last := errors[len(errors)-1]
+	if last.Code != TooManyErrors {
+		t.Errorf("last error has wrong code. got=%q", last.Code)
+	}
+}
+
+func TestNodePositions(t *testing.T) {
+	input := `let add = fn(a, b) {
+  a + b
+};
+add(1, [2][0])`
+
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	tests := []struct {
+		node          ast.Node
+		expectedStart string
+		expectedEnd   string
+	}{
+		{program, "1:1", "4:15"},
+		{program.Statements[0], "1:1", "3:2"},
+		{program.Statements[0].(*ast.LetStatement).Value, "1:11", "3:2"},
+		{program.Statements[1], "4:1", "4:15"},
+		{program.Statements[1].(*ast.ExpressionStatement).Expression.(*ast.CallExpression).Arguments[1], "4:8", "4:14"},
+	}
+
+	for i, tt := range tests {
+		if tt.node.Pos().String() != tt.expectedStart {
+			t.Errorf("tests[%d] - start wrong. expected=%q, got=%q",
+				i, tt.expectedStart, tt.node.Pos().String())
+		}
+		if tt.node.End().String() != tt.expectedEnd {
+			t.Errorf("tests[%d] - end wrong. expected=%q, got=%q",
+				i, tt.expectedEnd, tt.node.End().String())
+		}
+	}
+}
+
+func TestParsingComments(t *testing.T) {
+	input := `// the answer
+let x = 42; /* inline */ x // done`
+
+	l := lexer.New(input)
+	l.ScanComments()
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	if len(program.Statements) != 2 {
+		t.Fatalf("program.Statements does not contain 2 statements. got=%d",
+			len(program.Statements))
+	}
+
+	expected := []string{"// the answer", "/* inline */", "// done"}
+	if len(program.Comments) != len(expected) {
+		t.Fatalf("wrong number of comments. want=%d, got=%d",
+			len(expected), len(program.Comments))
+	}
+
+	for i, text := range expected {
+		if program.Comments[i].String() != text {
+			t.Errorf("comments[%d] wrong. want=%q, got=%q",
+				i, text, program.Comments[i].String())
+		}
 	}
 }
 